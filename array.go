@@ -0,0 +1,66 @@
+package pg_dao
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// ArrayOperator is the operator of ArrayCondition.
+type ArrayOperator string
+
+const (
+	ArrayOpContains    ArrayOperator = "@>"
+	ArrayOpOverlaps    ArrayOperator = "&&"
+	ArrayOpContainedBy ArrayOperator = "<@"
+	ArrayOpAny         ArrayOperator = "= ANY"
+)
+
+// ArrayCondition matches rows by the Column compared with a slice of Values passed as a single array
+// parameter, see Array. It is built by the array filters.
+type ArrayCondition struct {
+	Column string
+	Op     ArrayOperator
+	Values interface{}
+}
+
+func (c ArrayCondition) ToSql() (string, []interface{}, error) {
+	switch c.Op {
+	case ArrayOpContains, ArrayOpOverlaps, ArrayOpContainedBy:
+		return c.Column + " " + string(c.Op) + " ?", []interface{}{Array(c.Values)}, nil
+	case ArrayOpAny:
+		return c.Column + " = ANY(?)", []interface{}{Array(c.Values)}, nil
+	}
+	return "", nil, errors.From(errors.New("unexpected array operator"), map[string]interface{}{"op": c.Op})
+}
+
+// ArrayContains matches rows whose array column contains all of values: col @> values.
+func ArrayContains(col string, values interface{}) ArrayCondition {
+	return ArrayCondition{Column: col, Op: ArrayOpContains, Values: values}
+}
+
+// ArrayOverlaps matches rows whose array column has any of values: col && values.
+func ArrayOverlaps(col string, values interface{}) ArrayCondition {
+	return ArrayCondition{Column: col, Op: ArrayOpOverlaps, Values: values}
+}
+
+// ArrayContainedBy matches rows whose array column has only elements of values: col <@ values.
+func ArrayContainedBy(col string, values interface{}) ArrayCondition {
+	return ArrayCondition{Column: col, Op: ArrayOpContainedBy, Values: values}
+}
+
+// Any matches rows whose column equals any of values: col = ANY(values). Unlike FilterByColumn
+// with a slice it uses a single parameter for any number of values.
+func Any(col string, values interface{}) ArrayCondition {
+	return ArrayCondition{Column: col, Op: ArrayOpAny, Values: values}
+}
+
+// ArrayAppend appends val to the array column on Update: SET col = array_append(col, val).
+func (d *dao) ArrayAppend(col string, val interface{}) DAO {
+	return d.UpdateColumn(col, sq.Expr("array_append("+col+", ?)", val))
+}
+
+// ArrayRemove removes all elements equal to val from the array column on Update:
+// SET col = array_remove(col, val).
+func (d *dao) ArrayRemove(col string, val interface{}) DAO {
+	return d.UpdateColumn(col, sq.Expr("array_remove("+col+", ?)", val))
+}
@@ -0,0 +1,38 @@
+package pg_dao_test
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	pg "github.com/olegfomenko/pg-dao"
+)
+
+func TestArrayConditionSQL(t *testing.T) {
+	cases := []struct {
+		name string
+		cond sq.Sqlizer
+		sql  string
+	}{
+		{name: "contains", cond: pg.ArrayContains("tags", []string{"a", "b c"}), sql: "tags @> ?"},
+		{name: "overlaps", cond: pg.ArrayOverlaps("tags", []string{"a", "b c"}), sql: "tags && ?"},
+		{name: "contained by", cond: pg.ArrayContainedBy("tags", []string{"a", "b c"}), sql: "tags <@ ?"},
+		{name: "any", cond: pg.Any("tags", []string{"a", "b c"}), sql: "tags = ANY(?)"},
+	}
+	for _, c := range cases {
+		sql, args, err := c.cond.ToSql()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if sql != c.sql || len(args) != 1 {
+			t.Fatalf("%s: expected %s with one argument, got %s %v", c.name, c.sql, sql, args)
+		}
+		valuer, ok := args[0].(driver.Valuer)
+		if !ok {
+			t.Fatalf("%s: expected array parameter, got %T", c.name, args[0])
+		}
+		if val, err := valuer.Value(); err != nil || val != `{"a","b c"}` {
+			t.Fatalf("%s: expected {\"a\",\"b c\"}, got %v, %v", c.name, val, err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package pg_dao
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// AuditTable is the table audit entries are written to. See Migrations for its schema.
+const AuditTable = "audit_log"
+
+const redactedValue = "[REDACTED]"
+
+// AuditEntry is a single change of a record stored in AuditTable.
+// Before and After hold JSONB representation of the row and are nil when absent.
+type AuditEntry struct {
+	ID        int64     `db:"id"`
+	TableName string    `db:"table_name"`
+	RecordID  int64     `db:"record_id"`
+	Operation Operation `db:"operation"`
+	Before    []byte    `db:"before"`
+	After     []byte    `db:"after"`
+	Actor     *string   `db:"actor"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type actorKey struct{}
+
+// ContextWithActor returns a context carrying the actor that is written to the audit log.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored with ContextWithActor.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok
+}
+
+// WithAudit enables recording of every Create, Update and Delete into AuditTable
+// in the same transaction as the change itself. Values of redact columns are masked.
+func WithAudit(redact ...string) Option {
+	return func(o *options) {
+		o.audit = true
+		o.redact = make(map[string]bool, len(redact))
+		for _, col := range redact {
+			o.redact[col] = true
+		}
+	}
+}
+
+func (d *dao) AuditHistory(ctx context.Context, id int64) ([]AuditEntry, error) {
+	stmt := sq.Select("*").
+		From(AuditTable).
+		Where(sq.Eq{"table_name": d.tableName, "record_id": id}).
+		OrderBy(IdColumn + " " + OrderAscending)
+
+	var entries []AuditEntry
+	if err := d.query(ctx, &entries, stmt); err != nil {
+		return nil, errors.Wrap(err, "failed to select audit entries")
+	}
+	return entries, nil
+}
+
+type auditRow struct {
+	ID   int64  `db:"id"`
+	Data []byte `db:"data"`
+}
+
+// auditSnapshot selects JSONB representation of rows matching where, locking them if requested.
+func (d *dao) auditSnapshot(ctx context.Context, where sq.Sqlizer, lock bool) ([]auditRow, error) {
+	alias := d.tableName[strings.LastIndex(d.tableName, ".")+1:]
+	stmt := sq.Select(IdColumn, fmt.Sprintf("to_jsonb(%s) AS data", alias)).
+		From(d.tableName).
+		Where(where)
+	if lock {
+		stmt = stmt.Suffix("FOR UPDATE")
+	}
+
+	var rows []auditRow
+	if err := d.query(ctx, &rows, stmt); err != nil {
+		return nil, errors.Wrap(err, "failed to select audited rows")
+	}
+	return rows, nil
+}
+
+func (d *dao) auditCreate(ctx context.Context, id int64) error {
+	if !d.opts.audit {
+		return nil
+	}
+
+	after, err := d.auditSnapshot(ctx, sq.Eq{IdColumn: id}, false)
+	if err != nil {
+		return err
+	}
+	for _, row := range after {
+		if err := d.writeAudit(ctx, OpCreate, row.ID, nil, row.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dao) auditUpdate(ctx context.Context, where sq.And, update func() error) error {
+	before, err := d.auditSnapshot(ctx, where, true)
+	if err != nil {
+		return err
+	}
+	if err := update(); err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(before))
+	for _, row := range before {
+		ids = append(ids, row.ID)
+	}
+	after, err := d.auditSnapshot(ctx, sq.Eq{IdColumn: ids}, false)
+	if err != nil {
+		return err
+	}
+
+	changed := make(map[int64][]byte, len(after))
+	for _, row := range after {
+		changed[row.ID] = row.Data
+	}
+	for _, row := range before {
+		if err := d.writeAudit(ctx, OpUpdate, row.ID, row.Data, changed[row.ID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dao) auditDelete(ctx context.Context, where sq.And, delete func() error) error {
+	before, err := d.auditSnapshot(ctx, where, true)
+	if err != nil {
+		return err
+	}
+	if err := delete(); err != nil {
+		return err
+	}
+
+	for _, row := range before {
+		if err := d.writeAudit(ctx, OpDelete, row.ID, row.Data, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dao) writeAudit(ctx context.Context, op Operation, id int64, before, after []byte) error {
+	var actor *string
+	if a, ok := ActorFromContext(ctx); ok {
+		actor = &a
+	}
+
+	beforeVal, err := d.redact(before)
+	if err != nil {
+		return err
+	}
+	afterVal, err := d.redact(after)
+	if err != nil {
+		return err
+	}
+
+	stmt := sq.Insert(AuditTable).SetMap(map[string]interface{}{
+		"table_name": d.tableName,
+		"record_id":  id,
+		"operation":  op,
+		"before":     beforeVal,
+		"after":      afterVal,
+		"actor":      actor,
+		"created_at": d.opts.now(),
+	})
+	if _, err := d.exec(ctx, stmt); err != nil {
+		return errors.Wrap(err, "failed to write audit entry")
+	}
+	return nil
+}
+
+// redact masks redacted columns and returns the row as a JSONB parameter, nil for an absent row.
+func (d *dao) redact(data []byte) (interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	if len(d.opts.redact) == 0 {
+		return string(data), nil
+	}
+
+	var row map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&row); err != nil {
+		return nil, errors.Wrap(err, "failed to decode audited row")
+	}
+	for col := range d.opts.redact {
+		if _, ok := row[col]; ok {
+			row[col] = redactedValue
+		}
+	}
+
+	redacted, err := json.Marshal(row)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode audited row")
+	}
+	return string(redacted), nil
+}
@@ -0,0 +1,95 @@
+package pg_dao_test
+
+import (
+	"context"
+	"testing"
+
+	pg "github.com/olegfomenko/pg-dao"
+	"github.com/olegfomenko/pg-dao/pgdaotest"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type auditedEntry struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func countStatements(r *pgdaotest.Recorder, sql string) int {
+	n := 0
+	for _, s := range r.Statements() {
+		if s.SQL == sql {
+			n++
+		}
+	}
+	return n
+}
+
+func TestAuditedCreateRunsAfterCreateInTransaction(t *testing.T) {
+	hookErr := errors.New("hook failed")
+	r := pgdaotest.NewRecorder().Return(int64(7))
+	q := r.DAO("entries", pg.WithAudit(), pg.WithHooks(pg.Hooks{
+		AfterCreate: func(context.Context, int64, interface{}) error {
+			return hookErr
+		},
+	}))
+
+	id, err := q.Create(auditedEntry{Name: "a"})
+	if errors.Cause(err) != hookErr {
+		t.Fatalf("expected hook error, got %v", err)
+	}
+	if id != 0 {
+		t.Fatalf("expected no id of rolled back row, got %d", id)
+	}
+
+	statements := r.Statements()
+	if last := statements[len(statements)-1].SQL; last != "ROLLBACK" {
+		t.Fatalf("expected ROLLBACK after the hook, got %s", last)
+	}
+}
+
+func TestTransactionScopedToSession(t *testing.T) {
+	cases := []struct {
+		name   string
+		create func(root, q pg.DAO, r *pgdaotest.Recorder) error
+		begins int
+	}{
+		{
+			name: "derived from q",
+			create: func(_, q pg.DAO, _ *pgdaotest.Recorder) error {
+				_, err := q.New().Create(auditedEntry{Name: "a"})
+				return err
+			},
+			begins: 1,
+		},
+		{
+			name: "other session",
+			create: func(root, _ pg.DAO, _ *pgdaotest.Recorder) error {
+				_, err := root.New().Create(auditedEntry{Name: "a"})
+				return err
+			},
+			begins: 2,
+		},
+		{
+			name: "within transaction",
+			create: func(_, _ pg.DAO, r *pgdaotest.Recorder) error {
+				_, err := r.DAO("others", pg.WithAudit(), pg.WithinTransaction()).Create(auditedEntry{Name: "a"})
+				return err
+			},
+			begins: 1,
+		},
+	}
+
+	for _, c := range cases {
+		r := pgdaotest.NewRecorder().Return(int64(1))
+		root := r.DAO("entries", pg.WithAudit())
+		err := root.Transaction(func(q pg.DAO) error {
+			return c.create(root, q, r)
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if begins := countStatements(r, "BEGIN"); begins != c.begins {
+			t.Fatalf("%s: expected %d transactions, got %d", c.name, c.begins, begins)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Command pg-dao-ddl writes up and down migrations creating or altering a table described by an annotated
+// Go struct. Without -db the table is created, otherwise its current structure is diffed with the struct.
+//
+//	pg-dao-ddl -src ./models -type User -table users -out ./migrations
+//	pg-dao-ddl -src ./models -type User -table users -db "$DATABASE_URL" -out ./migrations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/olegfomenko/pg-dao/ddl"
+	"github.com/olegfomenko/pg-dao/introspect"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type config struct {
+	src, typeName, table, dbURL, out, name, version string
+	drop                                            bool
+}
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.src, "src", ".", "directory of the Go package declaring the struct")
+	flag.StringVar(&cfg.typeName, "type", "", "struct name")
+	flag.StringVar(&cfg.table, "table", "", "table name")
+	flag.StringVar(&cfg.dbURL, "db", "", "database URL to diff the table with, the table is created if empty")
+	flag.StringVar(&cfg.out, "out", ".", "directory to write migration files to")
+	flag.StringVar(&cfg.name, "name", "", "migration name, create_<table> or alter_<table> if empty")
+	flag.StringVar(&cfg.version, "version", time.Now().UTC().Format("20060102150405"), "migration version")
+	flag.BoolVar(&cfg.drop, "drop", false, "drop columns missing from the struct")
+	flag.Parse()
+
+	if err := run(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "pg-dao-ddl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg config) error {
+	if cfg.typeName == "" || cfg.table == "" {
+		return errors.New("-type and -table are required")
+	}
+
+	table, err := ddl.FromSource(cfg.src, cfg.typeName, cfg.table)
+	if err != nil {
+		return err
+	}
+
+	changes := []ddl.Change{{Up: strings.Join(table.Create(), ";\n"), Down: table.Drop()}}
+	if cfg.dbURL != "" {
+		changes, err = diff(cfg, table)
+		if err != nil {
+			return err
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+		return nil
+	}
+
+	name := cfg.name
+	if name == "" {
+		name = "create_" + cfg.table
+		if cfg.dbURL != "" && !strings.HasPrefix(changes[0].Up, "CREATE TABLE") {
+			name = "alter_" + cfg.table
+		}
+	}
+
+	up := make([]string, 0, len(changes))
+	down := make([]string, 0, len(changes))
+	for i := range changes {
+		up = append(up, changes[i].Up)
+		down = append(down, changes[len(changes)-1-i].Down)
+	}
+
+	files := []struct {
+		suffix string
+		stmts  []string
+	}{{"up", up}, {"down", down}}
+	for _, f := range files {
+		file := filepath.Join(cfg.out, fmt.Sprintf("%s_%s.%s.sql", cfg.version, name, f.suffix))
+		if err := os.WriteFile(file, []byte(strings.Join(f.stmts, ";\n\n")+";\n"), 0644); err != nil {
+			return errors.Wrap(err, "failed to write migration", map[string]interface{}{"file": file})
+		}
+		fmt.Println(file)
+	}
+	return nil
+}
+
+func diff(cfg config, table ddl.Table) ([]ddl.Change, error) {
+	db, err := pgdb.Open(pgdb.Opts{URL: cfg.dbURL, MaxOpenConnections: 1, MaxIdleConnections: 1})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open database")
+	}
+	defer db.RawDB().Close()
+
+	var opts []ddl.DiffOption
+	if cfg.drop {
+		opts = append(opts, ddl.WithDrop())
+	}
+	return ddl.DiffSchema(context.Background(), introspect.New(db), table, opts...)
+}
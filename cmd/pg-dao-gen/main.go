@@ -0,0 +1,148 @@
+// Command pg-dao-gen generates DTO structs and typed DAOs from a live database or a directory of SQL migrations.
+//
+//	pg-dao-gen -db postgres://localhost/app?sslmode=disable -pkg models -out models/models.gen.go
+//	pg-dao-gen -migrations ./migrations -tables users,posts -pkg models -out models/models.gen.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/olegfomenko/pg-dao/gen"
+	"github.com/olegfomenko/pg-dao/introspect"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// downMarker separates down part of sql-migrate style migrations.
+const downMarker = "-- +migrate Down"
+
+func main() {
+	var (
+		dbURL      = flag.String("db", "", "database URL to read the schema from")
+		migrations = flag.String("migrations", "", "directory of SQL migrations to read the schema from")
+		pkg        = flag.String("pkg", "models", "package name of the generated file")
+		out        = flag.String("out", "", "output file, stdout if empty")
+		tables     = flag.String("tables", "", "comma separated tables to generate, all if empty")
+	)
+	flag.Parse()
+
+	if err := run(*dbURL, *migrations, *pkg, *out, *tables); err != nil {
+		fmt.Fprintln(os.Stderr, "pg-dao-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dbURL, migrations, pkg, out, filter string) error {
+	var names []string
+	for _, name := range strings.Split(filter, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	var (
+		tables []gen.Table
+		err    error
+	)
+	switch {
+	case dbURL != "" && migrations != "":
+		return errors.New("only one of -db and -migrations can be set")
+	case dbURL != "":
+		tables, err = fromDatabase(dbURL, names)
+	case migrations != "":
+		tables, err = fromMigrations(migrations, names)
+	default:
+		return errors.New("one of -db and -migrations is required")
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkTables(tables, names); err != nil {
+		return err
+	}
+
+	src, err := gen.Generate(pkg, tables)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return errors.Wrap(os.WriteFile(out, src, 0644), "failed to write output")
+}
+
+func fromDatabase(url string, names []string) ([]gen.Table, error) {
+	db, err := pgdb.Open(pgdb.Opts{URL: url, MaxOpenConnections: 1, MaxIdleConnections: 1})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open database")
+	}
+	defer db.RawDB().Close()
+
+	return gen.FromDatabase(context.Background(), introspect.New(db), names...)
+}
+
+// fromMigrations applies up parts of *.sql files of the directory in name order.
+func fromMigrations(dir string, names []string) ([]gen.Table, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+	sort.Strings(files)
+
+	var scripts []string
+	for _, file := range files {
+		if strings.HasSuffix(file, ".down.sql") {
+			continue
+		}
+		script, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read migration", map[string]interface{}{"file": file})
+		}
+		scripts = append(scripts, strings.SplitN(string(script), downMarker, 2)[0])
+	}
+
+	tables, err := gen.FromSQL(scripts...)
+	if err != nil || len(names) == 0 {
+		return tables, err
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	filtered := tables[:0]
+	for _, t := range tables {
+		if wanted[t.Name] {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, nil
+}
+
+// checkTables reports requested tables that are missing from the schema and an empty schema.
+func checkTables(tables []gen.Table, names []string) error {
+	found := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		found[t.Name] = true
+	}
+	var missing []string
+	for _, name := range names {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("tables not found: %s", strings.Join(missing, ", "))
+	}
+	if len(tables) == 0 {
+		return errors.New("no tables found")
+	}
+	return nil
+}
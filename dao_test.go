@@ -0,0 +1,544 @@
+package pg_dao_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	pg "github.com/olegfomenko/pg-dao"
+	"github.com/olegfomenko/pg-dao/pgdaotest"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+const entriesTable = `
+CREATE TABLE entries (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    score      INTEGER     NOT NULL DEFAULT 0,
+    tags       TEXT[],
+    meta       JSONB,
+    version    BIGINT      NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ
+)`
+
+type entry struct {
+	ID        int64             `db:"id"`
+	Name      string            `db:"name"`
+	Score     int32             `db:"score"`
+	Tags      []string          `db:"tags"`
+	Meta      map[string]string `db:"meta"`
+	Version   int64             `db:"version"`
+	CreatedAt time.Time         `db:"created_at"`
+	UpdatedAt *time.Time        `db:"updated_at"`
+}
+
+var epoch = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// newEntries returns a DAO of the entries table in a database created for the test.
+// Timestamps are taken from *now.
+func newEntries(t *testing.T, now *time.Time, opts ...pg.Option) (*pgdb.DB, pg.DAO) {
+	t.Helper()
+
+	db := pgdaotest.NewDB(t,
+		pgdaotest.WithMigrationsFS(pg.Migrations, "migrations"),
+		pgdaotest.WithMigrations(entriesTable),
+	)
+	opts = append([]pg.Option{
+		pg.WithTimestamps(pg.CreatedAtColumn, pg.UpdatedAtColumn),
+		pg.WithClock(func() time.Time { return *now }),
+	}, opts...)
+	return db, pg.NewDAO(db, "entries", opts...)
+}
+
+func create(t *testing.T, q pg.DAO, entries ...entry) []int64 {
+	t.Helper()
+
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		id, err := q.New().Create(e)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", e.Name, err)
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+func get(t *testing.T, q pg.DAO, id int64) (entry, bool) {
+	t.Helper()
+
+	var e entry
+	ok, err := q.New().FilterByID(id).Get(&e)
+	if err != nil {
+		t.Fatalf("failed to get %d: %v", id, err)
+	}
+	return e, ok
+}
+
+func names(entries []entry) []string {
+	result := make([]string, len(entries))
+	for i, e := range entries {
+		result[i] = e.Name
+	}
+	return result
+}
+
+func expectNames(t *testing.T, q pg.DAO, expected ...string) {
+	t.Helper()
+
+	var list []entry
+	if err := q.Select(&list); err != nil {
+		t.Fatalf("failed to select: %v", err)
+	}
+	if got := names(list); !reflect.DeepEqual(got, expected) && !(len(got) == 0 && len(expected) == 0) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestCreateGet(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+
+	id, err := q.New().Create(entry{
+		Name:  "first",
+		Score: 7,
+		Tags:  []string{"a", "b c"},
+		Meta:  map[string]string{"plan": "pro"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	got, ok := get(t, q, id)
+	if !ok {
+		t.Fatal("created entry is not found")
+	}
+	expected := entry{
+		ID:      id,
+		Name:    "first",
+		Score:   7,
+		Tags:    []string{"a", "b c"},
+		Meta:    map[string]string{"plan": "pro"},
+		Version: 1,
+	}
+	if !got.CreatedAt.Equal(epoch) {
+		t.Fatalf("expected created_at %s, got %s", epoch, got.CreatedAt)
+	}
+	got.CreatedAt = time.Time{}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	if _, ok := get(t, q, id+1); ok {
+		t.Fatal("missing entry is found")
+	}
+
+	if _, err := q.New().CreateCtx(context.Background(), map[string]interface{}{"name": "map"}); err != nil {
+		t.Fatalf("failed to create from map: %v", err)
+	}
+	var e entry
+	if ok, err := q.New().FilterByColumn("name", "map").GetCtx(context.Background(), &e); err != nil || !ok {
+		t.Fatalf("failed to get entry created from map: %v, %v", ok, err)
+	}
+}
+
+func TestBulkCreate(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+
+	ids, err := q.New().BulkCreate([]interface{}{
+		entry{Name: "first", Score: 3},
+		map[string]interface{}{"name": "second"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if len(ids) != 2 || ids[1] <= ids[0] {
+		t.Fatalf("expected ids in the order of dtos, got %v", ids)
+	}
+
+	first, _ := get(t, q, ids[0])
+	second, _ := get(t, q, ids[1])
+	if first.Name != "first" || first.Score != 3 || second.Name != "second" || second.Score != 0 {
+		t.Fatalf("unexpected entries %+v, %+v", first, second)
+	}
+	if !second.CreatedAt.Equal(epoch) || second.Version != 1 {
+		t.Fatalf("managed columns are not set: %+v", second)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+	for i, name := range []string{"a", "b", "c", "d"} {
+		now = epoch.Add(time.Duration(i) * time.Hour)
+		create(t, q, entry{Name: name, Score: int32(i), Tags: []string{name}, Meta: map[string]string{"k": name}})
+	}
+
+	expectNames(t, q.New().OrderByAsc("id"), "a", "b", "c", "d")
+	expectNames(t, q.New().OrderByDesc("id"), "d", "c", "b", "a")
+	expectNames(t, q.New().OrderByAsc("id").Limit(2), "a", "b")
+	expectNames(t, q.New().FilterByColumn("name", "c"), "c")
+	expectNames(t, q.New().FilterGreater("score", 1).OrderByAsc("id"), "c", "d")
+	expectNames(t, q.New().FilterLess("score", 1), "a")
+	expectNames(t, q.New().FilterOnlyAfter(epoch.Add(time.Hour)).OrderByAsc("id"), "c", "d")
+	expectNames(t, q.New().FilterOnlyBefore(epoch.Add(time.Hour)), "a")
+	expectNames(t, q.New().Filter(pg.ArrayContains("tags", []string{"b"})), "b")
+	expectNames(t, q.New().Filter(pg.JSONContains("meta", map[string]string{"k": "d"})), "d")
+	expectNames(t, q.New().Filter(sq.Eq{"name": []string{"a", "d"}}).OrderByAsc("id"), "a", "d")
+	expectNames(t, q.Clone().FilterByColumn("name", "a"), "a")
+
+	var count int64
+	if _, err := q.Count().FilterGreater("score", 0).Get(&count); err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 entries, counted %d", count)
+	}
+
+	var list []entry
+	if err := q.New().FilterByColumn("name", "b").SelectCtx(context.Background(), &list); err != nil {
+		t.Fatalf("failed to select: %v", err)
+	}
+	if len(list) != 1 || list[0].Meta["k"] != "b" {
+		t.Fatalf("unexpected entries %+v", list)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+	create(t, q, entry{Name: "green apples"}, entry{Name: "red apple pie"}, entry{Name: "bananas"})
+
+	expectNames(t, q.New().Search([]string{"name"}, "apple -pie", pg.SearchOptions{Config: "english"}),
+		"green apples")
+
+	var count int64
+	if _, err := q.Count().Search([]string{"name"}, "apple", pg.SearchOptions{Config: "english"}).Get(&count); err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 entries, counted %d", count)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+	ids := create(t, q, entry{Name: "a"}, entry{Name: "b"}, entry{Name: "c"}, entry{Name: "d"}, entry{Name: "e"})
+
+	expectNames(t, q.New().Page(pgdb.OffsetPageParams{Limit: 2, PageNumber: 1, Order: pgdb.OrderTypeAsc}, "id"),
+		"c", "d")
+	expectNames(t, q.New().Page(pgdb.OffsetPageParams{Limit: 2}, "id"), "e", "d")
+	expectNames(t, q.New().Cursor(pgdb.CursorPageParams{Cursor: uint64(ids[1]), Limit: 2, Order: pgdb.OrderTypeAsc}, "id"),
+		"c", "d")
+	expectNames(t, q.New().Cursor(pgdb.CursorPageParams{Cursor: uint64(ids[3]), Limit: 2}, "id"), "c", "b")
+
+	var list []entry
+	if err := q.New().Page(pgdb.OffsetPageParams{Order: "sideways"}, "id").Select(&list); err == nil {
+		t.Fatal("expected error for unexpected order")
+	}
+	if err := q.New().Cursor(pgdb.CursorPageParams{Order: "sideways"}, "id").Select(&list); err == nil {
+		t.Fatal("expected error for unexpected order")
+	}
+
+	columns := []pg.KeysetColumn{{Name: "id"}}
+	page, err := pg.SelectPage(q.New(), &list, pg.KeysetParams{Columns: columns, Limit: 2})
+	if err != nil {
+		t.Fatalf("failed to select first keyset page: %v", err)
+	}
+	if got := names(list); !reflect.DeepEqual(got, []string{"a", "b"}) || page.Next == "" || page.Prev != "" {
+		t.Fatalf("unexpected first page %v %+v", got, page)
+	}
+	list = nil
+	page, err = pg.SelectPage(q.New(), &list, pg.KeysetParams{Columns: columns, Limit: 2, Cursor: page.Next})
+	if err != nil {
+		t.Fatalf("failed to select second keyset page: %v", err)
+	}
+	if got := names(list); !reflect.DeepEqual(got, []string{"c", "d"}) || page.Next == "" || page.Prev == "" {
+		t.Fatalf("unexpected second page %v %+v", got, page)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+	ids := create(t, q, entry{Name: "a", Score: 10, Tags: []string{"x"}}, entry{Name: "b", Score: 20})
+
+	now = epoch.Add(time.Hour)
+	err := q.New().UpdateWhereID(ids[0]).
+		UpdateColumn("name", "a2").
+		Increment("score", 5).
+		ArrayAppend("tags", "y").
+		Update()
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	got, _ := get(t, q, ids[0])
+	if got.Name != "a2" || got.Score != 15 || !reflect.DeepEqual(got.Tags, []string{"x", "y"}) {
+		t.Fatalf("unexpected updated entry %+v", got)
+	}
+	if got.UpdatedAt == nil || !got.UpdatedAt.Equal(now) {
+		t.Fatalf("expected updated_at %s, got %v", now, got.UpdatedAt)
+	}
+
+	explicit := epoch.Add(-time.Hour)
+	err = q.New().UpdateWhereID(ids[0]).
+		Decrement("score", 3).
+		ArrayRemove("tags", "x").
+		UpdateColumn(pg.UpdatedAtColumn, explicit).
+		UpdateCtx(context.Background())
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	got, _ = get(t, q, ids[0])
+	if got.Score != 12 || !reflect.DeepEqual(got.Tags, []string{"y"}) || !got.UpdatedAt.Equal(explicit) {
+		t.Fatalf("unexpected updated entry %+v", got)
+	}
+
+	if err := q.New().UpdateWhereID(ids[1]).UpdateExpr("score", sq.Expr("score * 2")).Update(); err != nil {
+		t.Fatalf("failed to update with expression: %v", err)
+	}
+	got, _ = get(t, q, ids[1])
+	if got.Score != 40 {
+		t.Fatalf("expected score 40, got %d", got.Score)
+	}
+
+	got.Name, got.Meta = "b2", map[string]string{"k": "v"}
+	if err := q.New().UpdateWhereID(ids[1]).UpdateDTO(got).Update(); err != nil {
+		t.Fatalf("failed to update dto: %v", err)
+	}
+	got, _ = get(t, q, ids[1])
+	if got.Name != "b2" || got.Meta["k"] != "v" {
+		t.Fatalf("unexpected updated entry %+v", got)
+	}
+
+	err = q.New().UpdateWhereID(ids[1]+100).UpdateColumn("name", "missing").Update()
+	if errors.Cause(err) != pg.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := q.New().UpdateColumn("name", "all").Update(); errors.Cause(err) != pg.ErrUnfiltered {
+		t.Fatalf("expected ErrUnfiltered, got %v", err)
+	}
+
+	rows, err := q.New().UpdateWhere(sq.Gt{"score": 0}).UpdateColumn("name", "positive").BulkUpdate()
+	if err != nil || rows != 2 {
+		t.Fatalf("expected 2 updated rows, got %d, %v", rows, err)
+	}
+	rows, err = q.New().UpdateWhere(sq.Lt{"score": 0}).UpdateColumn("name", "negative").BulkUpdateCtx(context.Background())
+	if err != nil || rows != 0 {
+		t.Fatalf("expected no updated rows, got %d, %v", rows, err)
+	}
+	rows, err = q.New().AllowFullTable().UpdateColumn("score", 0).BulkUpdate()
+	if err != nil || rows != 2 {
+		t.Fatalf("expected 2 updated rows, got %d, %v", rows, err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now, pg.WithVersion("version"))
+	ids := create(t, q, entry{Name: "a"})
+
+	if err := q.New().UpdateWhereID(ids[0]).UpdateWhereVersion(1).UpdateColumn("name", "b").Update(); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	err := q.New().UpdateWhereID(ids[0]).UpdateWhereVersion(1).UpdateColumn("name", "c").Update()
+	if errors.Cause(err) != pg.ErrStaleVersion {
+		t.Fatalf("expected ErrStaleVersion, got %v", err)
+	}
+
+	got, _ := get(t, q, ids[0])
+	if got.Name != "b" || got.Version != 2 {
+		t.Fatalf("unexpected entry %+v", got)
+	}
+	if err := q.New().UpdateWhereID(ids[0]).UpdateColumn("name", "c").Update(); err == nil {
+		t.Fatal("expected error for update without version")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+	ids := create(t, q, entry{Name: "a", Score: 1}, entry{Name: "b", Score: 2}, entry{Name: "c", Score: 3},
+		entry{Name: "d", Score: 4}, entry{Name: "e", Score: 5})
+
+	if err := q.New().DeleteWhereID(ids[0]).Delete(); err != nil {
+		t.Fatalf("failed to delete by id: %v", err)
+	}
+	if err := q.New().DeleteWhereVal("name", "b").DeleteCtx(context.Background()); err != nil {
+		t.Fatalf("failed to delete by value: %v", err)
+	}
+	rows, err := q.New().DeleteWhere(sq.Gt{"score": 3}).BulkDelete()
+	if err != nil || rows != 2 {
+		t.Fatalf("expected 2 deleted rows, got %d, %v", rows, err)
+	}
+	expectNames(t, q.New(), "c")
+
+	if err := q.New().Delete(); errors.Cause(err) != pg.ErrUnfiltered {
+		t.Fatalf("expected ErrUnfiltered, got %v", err)
+	}
+	rows, err = q.New().AllowFullTable().BulkDeleteCtx(context.Background())
+	if err != nil || rows != 1 {
+		t.Fatalf("expected 1 deleted row, got %d, %v", rows, err)
+	}
+	expectNames(t, q.New())
+}
+
+func TestTransaction(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+
+	err := q.Transaction(func(tx pg.DAO) error {
+		create(t, tx, entry{Name: "committed"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	failure := errors.New("rollback")
+	err = q.Transaction(func(tx pg.DAO) error {
+		create(t, tx, entry{Name: "rolled back"})
+		return failure
+	})
+	if errors.Cause(err) != failure {
+		t.Fatalf("expected the error of the transaction, got %v", err)
+	}
+	expectNames(t, q.New(), "committed")
+
+	level := func(tx pg.DAO) string {
+		var level string
+		err := tx.ExecRaw(func(raw *pgdb.DB) error {
+			return raw.GetRaw(&level, "SHOW transaction_isolation")
+		})
+		if err != nil {
+			t.Fatalf("failed to get isolation level: %v", err)
+		}
+		return level
+	}
+
+	err = q.TransactionSerializable(func(tx pg.DAO) error {
+		if got := level(tx); got != "serializable" {
+			t.Errorf("expected serializable transaction, got %s", got)
+		}
+		create(t, tx, entry{Name: "serializable"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to commit serializable transaction: %v", err)
+	}
+
+	err = q.TransactionWithLevel(sql.LevelRepeatableRead, func(tx pg.DAO) error {
+		if got := level(tx); got != "repeatable read" {
+			t.Errorf("expected repeatable read transaction, got %s", got)
+		}
+		return tx.New().UpdateWhere(sq.Eq{"name": "serializable"}).UpdateColumn("name", "repeatable").Update()
+	})
+	if err != nil {
+		t.Fatalf("failed to commit repeatable read transaction: %v", err)
+	}
+	expectNames(t, q.New().OrderByAsc("id"), "committed", "repeatable")
+}
+
+func TestAudit(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now, pg.WithAudit())
+
+	ctx := pg.ContextWithActor(context.Background(), "tester")
+	id, err := q.New().CreateCtx(ctx, entry{Name: "a"})
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if err := q.New().UpdateWhereID(id).UpdateColumn("name", "b").UpdateCtx(ctx); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if err := q.New().DeleteWhereID(id).DeleteCtx(ctx); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	history, err := q.New().AuditHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to get audit history: %v", err)
+	}
+	ops := make([]pg.Operation, len(history))
+	for i, e := range history {
+		ops[i] = e.Operation
+		if e.Actor == nil || *e.Actor != "tester" {
+			t.Errorf("unexpected actor of %s: %v", e.Operation, e.Actor)
+		}
+	}
+	if expected := []pg.Operation{pg.OpCreate, pg.OpUpdate, pg.OpDelete}; !reflect.DeepEqual(ops, expected) {
+		t.Fatalf("expected %v, got %v", expected, ops)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+
+	if err := q.Validate(context.Background(), entry{}); err != nil {
+		t.Fatalf("expected entry to match the table: %v", err)
+	}
+
+	type broken struct {
+		ID    int64  `db:"id"`
+		Score string `db:"score"`
+		Extra int64  `db:"extra"`
+	}
+	err := q.Validate(context.Background(), broken{})
+	verr, ok := err.(*pg.ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if len(verr.Problems) == 0 {
+		t.Fatal("expected validation problems")
+	}
+}
+
+func TestDryRunAndToSQL(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+
+	query, args, err := q.New().FilterByID(5).ToSQL()
+	if err != nil {
+		t.Fatalf("failed to build query: %v", err)
+	}
+	if expected := "SELECT entries.* FROM entries WHERE id = $1"; query != expected ||
+		!reflect.DeepEqual(args, []interface{}{int64(5)}) {
+		t.Fatalf("expected %s [5], got %s %v", expected, query, args)
+	}
+
+	_, err = q.New().DryRun().Create(entry{Name: "a"})
+	if _, ok := err.(*pg.DryRunStatement); !ok {
+		t.Fatalf("expected *DryRunStatement, got %v", err)
+	}
+	err = q.New().DryRun().UpdateWhereID(1).UpdateColumn("name", "b").Update()
+	if _, ok := err.(*pg.DryRunStatement); !ok {
+		t.Fatalf("expected *DryRunStatement, got %v", err)
+	}
+	err = q.New().DryRun().DeleteWhereID(1).Delete()
+	if _, ok := err.(*pg.DryRunStatement); !ok {
+		t.Fatalf("expected *DryRunStatement, got %v", err)
+	}
+	expectNames(t, q.New())
+}
+
+func TestExecRaw(t *testing.T) {
+	now := epoch
+	_, q := newEntries(t, &now)
+	create(t, q, entry{Name: "a"})
+
+	var count int64
+	err := q.ExecRawCtx(context.Background(), func(ctx context.Context, raw *pgdb.DB) error {
+		return raw.GetRawContext(ctx, &count, "SELECT count(*) FROM entries")
+	})
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 entry, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,305 @@
+// Package ddl generates CREATE TABLE and ALTER TABLE statements from annotated DTO structs.
+//
+// Columns are named by `db` tags and their types are inferred from Go types. The `pg` tag
+// overrides the type and sets other column options:
+//
+//	type User struct {
+//		ID        int64             `db:"id"`
+//		Email     string            `db:"email" pg:"type=varchar(255),unique"`
+//		Meta      map[string]string `db:"meta" pg:"default='{}'"`
+//		CreatedAt time.Time         `db:"created_at" pg:"default=now(),index"`
+//	}
+//
+// Supported options are type=<sql type>, pk, null, notnull, default=<expression>,
+// unique[=<index name>] and index[=<index name>]. Fields sharing an index name form a composite index.
+// Integer "id" column without explicit type becomes a serial primary key. Enums declared as named
+// string types become text columns unless the enum type is set, e.g. `pg:"type=mood"`.
+package ddl
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	pg "github.com/olegfomenko/pg-dao"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// TagName is the struct tag holding column options.
+const TagName = "pg"
+
+// Column is a column definition.
+type Column struct {
+	Name       string
+	Type       string
+	NotNull    bool
+	Default    string
+	PrimaryKey bool
+}
+
+// Index is an index definition.
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// Table is a table definition.
+type Table struct {
+	Name    string
+	Columns []Column
+	Indexes []Index
+}
+
+// Field describes a struct field a column is built from.
+type Field struct {
+	// Name is the Go field name, used in errors.
+	Name string
+	// Type is the Go type as written in source, e.g. "*time.Time" or "pq.StringArray".
+	Type string
+	Tag  reflect.StructTag
+
+	// kind is the underlying kind of the type if known, used for types without a predefined mapping.
+	kind reflect.Kind
+	// elem is the kind of slice elements if known.
+	elem reflect.Kind
+}
+
+// FromStruct builds the table definition from fields of the dto struct. Embedded structs are flattened
+// unless tagged with a column name.
+func FromStruct(table string, dto interface{}) (Table, error) {
+	t := reflect.TypeOf(dto)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return Table{}, errors.From(errors.New("dto must be a struct"), map[string]interface{}{"table": table})
+	}
+	return FromFields(table, structFields(t))
+}
+
+func structFields(t reflect.Type) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := columnName(f.Tag)
+		if name == "-" {
+			continue
+		}
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, structFields(ft)...)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		kt := ft
+		for kt.Kind() == reflect.Ptr {
+			kt = kt.Elem()
+		}
+		var elem reflect.Kind
+		if kt.Kind() == reflect.Slice {
+			elem = kt.Elem().Kind()
+		}
+		fields = append(fields, Field{Name: f.Name, Type: ft.String(), Tag: f.Tag, kind: kt.Kind(), elem: elem})
+	}
+	return fields
+}
+
+// FromFields builds the table definition from field descriptions.
+func FromFields(table string, fields []Field) (Table, error) {
+	t := Table{Name: table}
+	indexes := make(map[string]int)
+
+	addIndex := func(name string, unique bool, col string) {
+		if name == "" {
+			suffix := "idx"
+			if unique {
+				suffix = "key"
+			}
+			name = fmt.Sprintf("%s_%s_%s", table, col, suffix)
+		}
+		if i, ok := indexes[name]; ok {
+			t.Indexes[i].Columns = append(t.Indexes[i].Columns, col)
+			t.Indexes[i].Unique = t.Indexes[i].Unique || unique
+			return
+		}
+		indexes[name] = len(t.Indexes)
+		t.Indexes = append(t.Indexes, Index{Name: name, Columns: []string{col}, Unique: unique})
+	}
+
+	for _, f := range fields {
+		name := columnName(f.Tag)
+		if name == "" || name == "-" {
+			continue
+		}
+
+		opts := parseOptions(f.Tag.Get(TagName))
+		c := Column{Name: name, NotNull: !nullable(f.Type, f.kind)}
+		for _, opt := range strings.Split(f.Tag.Get("db"), ",")[1:] {
+			switch opt {
+			case pg.TagJSON:
+				c.Type = "jsonb"
+			case pg.TagHstore:
+				c.Type = "hstore"
+			}
+		}
+		for _, opt := range opts {
+			if opt.key == "type" {
+				c.Type = opt.val
+			}
+		}
+		if c.Type == "" {
+			typ, ok := sqlType(f.Type, f.kind, f.elem)
+			if !ok {
+				return Table{}, errors.From(errors.New("unknown column type, set it with pg:\"type=...\""),
+					map[string]interface{}{"table": table, "field": f.Name, "type": f.Type})
+			}
+			c.Type = typ
+			if name == pg.IdColumn {
+				switch typ {
+				case "bigint":
+					c.Type, c.PrimaryKey = "bigserial", true
+				case "integer":
+					c.Type, c.PrimaryKey = "serial", true
+				}
+			}
+		}
+
+		for _, opt := range opts {
+			switch opt.key {
+			case "type":
+			case "pk":
+				c.PrimaryKey = true
+			case "null":
+				c.NotNull = false
+			case "notnull":
+				c.NotNull = true
+			case "default":
+				c.Default = opt.val
+			case "unique":
+				addIndex(opt.val, true, name)
+			case "index":
+				addIndex(opt.val, false, name)
+			default:
+				return Table{}, errors.From(errors.New("unknown pg tag option"),
+					map[string]interface{}{"table": table, "field": f.Name, "option": opt.key})
+			}
+		}
+		if c.PrimaryKey {
+			c.NotNull = true
+		}
+		t.Columns = append(t.Columns, c)
+	}
+
+	if len(t.Columns) == 0 {
+		return Table{}, errors.From(errors.New("no columns"), map[string]interface{}{"table": table})
+	}
+	return t, nil
+}
+
+// Create returns CREATE TABLE and CREATE INDEX statements of the table.
+func (t Table) Create() []string {
+	var pk []string
+	for _, c := range t.Columns {
+		if c.PrimaryKey {
+			pk = append(pk, c.Name)
+		}
+	}
+
+	defs := make([]string, 0, len(t.Columns)+1)
+	for _, c := range t.Columns {
+		// a composite primary key is a table constraint
+		c.PrimaryKey = c.PrimaryKey && len(pk) == 1
+		defs = append(defs, c.definition())
+	}
+	if len(pk) > 1 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pk, ", ")))
+	}
+
+	stmts := []string{fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", t.Name, strings.Join(defs, ",\n\t"))}
+	for _, idx := range t.Indexes {
+		stmts = append(stmts, idx.create(t.Name))
+	}
+	return stmts
+}
+
+// Drop returns the DROP TABLE statement of the table.
+func (t Table) Drop() string {
+	return fmt.Sprintf("DROP TABLE %s", t.Name)
+}
+
+func (c Column) definition() string {
+	def := c.Name + " " + c.Type
+	if c.NotNull {
+		def += " NOT NULL"
+	}
+	if c.Default != "" {
+		def += " DEFAULT " + c.Default
+	}
+	if c.PrimaryKey {
+		def += " PRIMARY KEY"
+	}
+	return def
+}
+
+func (idx Index) create(table string) string {
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, idx.Name, table, strings.Join(idx.Columns, ", "))
+}
+
+func columnName(tag reflect.StructTag) string {
+	return strings.Split(tag.Get("db"), ",")[0]
+}
+
+type option struct {
+	key, val string
+}
+
+// parseOptions splits the tag by commas outside of parentheses and quotes.
+func parseOptions(tag string) []option {
+	var (
+		opts  []option
+		depth int
+		quote bool
+		start int
+	)
+	add := func(part string) {
+		if part = strings.TrimSpace(part); part == "" {
+			return
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		opts = append(opts, option{key: strings.TrimSpace(kv[0]), val: strings.TrimSpace(kv[1])})
+	}
+
+	for i := 0; i < len(tag); i++ {
+		switch c := tag[i]; {
+		case c == '\'':
+			quote = !quote
+		case quote:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			add(tag[start:i])
+			start = i + 1
+		}
+	}
+	add(tag[start:])
+	return opts
+}
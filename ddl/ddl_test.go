@@ -0,0 +1,245 @@
+package ddl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/olegfomenko/pg-dao/introspect"
+)
+
+func TestFromFields(t *testing.T) {
+	cases := []struct {
+		name     string
+		fields   []Field
+		expected Table
+		fails    bool
+	}{
+		{
+			name: "inferred types",
+			fields: []Field{
+				{Name: "ID", Type: "int64", Tag: `db:"id"`},
+				{Name: "Name", Type: "*string", Tag: `db:"name"`},
+				{Name: "Tags", Type: "[]string", Tag: `db:"tags"`},
+				{Name: "Meta", Type: "Meta", Tag: `db:"meta,json"`},
+				{Name: "CreatedAt", Type: "time.Time", Tag: `db:"created_at" pg:"default=now()"`},
+				{Name: "Skipped", Type: "string", Tag: `db:"-"`},
+				{Name: "Untagged", Type: "string"},
+			},
+			expected: Table{Name: "users", Columns: []Column{
+				{Name: "id", Type: "bigserial", NotNull: true, PrimaryKey: true},
+				{Name: "name", Type: "text"},
+				{Name: "tags", Type: "text[]"},
+				{Name: "meta", Type: "jsonb", NotNull: true},
+				{Name: "created_at", Type: "timestamp with time zone", NotNull: true, Default: "now()"},
+			}},
+		},
+		{
+			name: "options",
+			fields: []Field{
+				{Name: "Email", Type: "string", Tag: `db:"email" pg:"type=varchar(255),unique,null"`},
+				{Name: "Org", Type: "int32", Tag: `db:"org_id" pg:"index=users_org_name,notnull"`},
+				{Name: "Name", Type: "*string", Tag: `db:"name" pg:"index=users_org_name,default='a,b'"`},
+				{Name: "Mood", Type: "Mood", Tag: `db:"mood" pg:"type=mood"`},
+			},
+			expected: Table{
+				Name: "users",
+				Columns: []Column{
+					{Name: "email", Type: "varchar(255)"},
+					{Name: "org_id", Type: "integer", NotNull: true},
+					{Name: "name", Type: "text", Default: "'a,b'"},
+					{Name: "mood", Type: "mood", NotNull: true},
+				},
+				Indexes: []Index{
+					{Name: "users_email_key", Columns: []string{"email"}, Unique: true},
+					{Name: "users_org_name", Columns: []string{"org_id", "name"}},
+				},
+			},
+		},
+		{
+			name:   "unknown type",
+			fields: []Field{{Name: "Point", Type: "geo.Point", Tag: `db:"point"`}},
+			fails:  true,
+		},
+		{
+			name:   "unknown option",
+			fields: []Field{{Name: "Name", Type: "string", Tag: `db:"name" pg:"size=10"`}},
+			fails:  true,
+		},
+		{
+			name:   "no columns",
+			fields: []Field{{Name: "Name", Type: "string"}},
+			fails:  true,
+		},
+	}
+
+	for _, c := range cases {
+		table, err := FromFields("users", c.fields)
+		if c.fails {
+			if err == nil {
+				t.Fatalf("%s: expected error, got %+v", c.name, table)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(table, c.expected) {
+			t.Fatalf("%s: expected %+v, got %+v", c.name, c.expected, table)
+		}
+	}
+}
+
+func TestFromStruct(t *testing.T) {
+	type base struct {
+		ID int64 `db:"id"`
+	}
+	type user struct {
+		base
+		Scores    []int32    `db:"scores"`
+		DeletedAt *time.Time `db:"deleted_at"`
+		hidden    string
+	}
+
+	table, err := FromStruct("users", &user{})
+	if err != nil {
+		t.Fatalf("from struct: %v", err)
+	}
+	expected := Table{Name: "users", Columns: []Column{
+		{Name: "id", Type: "bigserial", NotNull: true, PrimaryKey: true},
+		{Name: "scores", Type: "integer[]"},
+		{Name: "deleted_at", Type: "timestamp with time zone"},
+	}}
+	if !reflect.DeepEqual(table, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, table)
+	}
+
+	if _, err := FromStruct("users", 1); err == nil {
+		t.Fatalf("expected error for a non-struct dto")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	cases := []struct {
+		name     string
+		table    Table
+		expected []string
+	}{
+		{
+			name: "single primary key",
+			table: Table{
+				Name: "users",
+				Columns: []Column{
+					{Name: "id", Type: "bigserial", NotNull: true, PrimaryKey: true},
+					{Name: "email", Type: "text", NotNull: true, Default: "''"},
+				},
+				Indexes: []Index{{Name: "users_email_key", Columns: []string{"email"}, Unique: true}},
+			},
+			expected: []string{
+				"CREATE TABLE users (\n\tid bigserial NOT NULL PRIMARY KEY,\n\temail text NOT NULL DEFAULT ''\n)",
+				"CREATE UNIQUE INDEX users_email_key ON users (email)",
+			},
+		},
+		{
+			name: "composite primary key",
+			table: Table{
+				Name: "members",
+				Columns: []Column{
+					{Name: "org_id", Type: "bigint", NotNull: true, PrimaryKey: true},
+					{Name: "user_id", Type: "bigint", NotNull: true, PrimaryKey: true},
+					{Name: "role", Type: "text"},
+				},
+				Indexes: []Index{{Name: "members_role_idx", Columns: []string{"role", "org_id"}}},
+			},
+			expected: []string{
+				"CREATE TABLE members (\n\torg_id bigint NOT NULL,\n\tuser_id bigint NOT NULL,\n\trole text,\n" +
+					"\tPRIMARY KEY (org_id, user_id)\n)",
+				"CREATE INDEX members_role_idx ON members (role, org_id)",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		if got := c.table.Create(); !reflect.DeepEqual(got, c.expected) {
+			t.Fatalf("%s: expected %q, got %q", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	def := func(s string) *string { return &s }
+	table := Table{
+		Name: "users",
+		Columns: []Column{
+			{Name: "id", Type: "bigserial", NotNull: true, PrimaryKey: true},
+			{Name: "email", Type: "varchar(255)", NotNull: true},
+			{Name: "score", Type: "bigint", NotNull: true, Default: "0"},
+			{Name: "nickname", Type: "text"},
+		},
+		Indexes: []Index{{Name: "users_email_key", Columns: []string{"email"}, Unique: true}},
+	}
+	columns := []introspect.Column{
+		{Name: "id", DataType: "bigint", UDTName: "int8", Default: def("nextval('users_id_seq'::regclass)")},
+		{Name: "email", DataType: "character varying", UDTName: "varchar", Nullable: true},
+		{Name: "score", DataType: "integer", UDTName: "int4", Default: def("1")},
+		{Name: "legacy", DataType: "text", UDTName: "text", Nullable: true},
+	}
+
+	cases := []struct {
+		name     string
+		columns  []introspect.Column
+		indexes  []introspect.Index
+		opts     []DiffOption
+		expected []Change
+	}{
+		{
+			name: "new table",
+			expected: []Change{{
+				Up: "CREATE TABLE users (\n\tid bigserial NOT NULL PRIMARY KEY,\n\temail varchar(255) NOT NULL,\n" +
+					"\tscore bigint NOT NULL DEFAULT 0,\n\tnickname text\n);\n" +
+					"CREATE UNIQUE INDEX users_email_key ON users (email)",
+				Down: "DROP TABLE users",
+			}},
+		},
+		{
+			name:    "existing table",
+			columns: columns,
+			indexes: []introspect.Index{{Name: "users_email_key"}},
+			expected: []Change{
+				{
+					Up:   "ALTER TABLE users ALTER COLUMN email SET NOT NULL",
+					Down: "ALTER TABLE users ALTER COLUMN email DROP NOT NULL",
+				},
+				{
+					Up:   "ALTER TABLE users ALTER COLUMN score TYPE bigint USING score::bigint",
+					Down: "ALTER TABLE users ALTER COLUMN score TYPE integer USING score::integer",
+				},
+				{Up: "ALTER TABLE users ADD COLUMN nickname text", Down: "ALTER TABLE users DROP COLUMN nickname"},
+			},
+		},
+		{
+			name:    "drop columns",
+			columns: columns[3:],
+			indexes: []introspect.Index{{Name: "users_email_key"}},
+			opts:    []DiffOption{WithDrop()},
+			expected: []Change{
+				{Up: "ALTER TABLE users ADD COLUMN id bigserial NOT NULL PRIMARY KEY", Down: "ALTER TABLE users DROP COLUMN id"},
+				{
+					Up: "-- email is added nullable: fill it for existing rows, then SET NOT NULL\n" +
+						"ALTER TABLE users ADD COLUMN email varchar(255)",
+					Down: "ALTER TABLE users DROP COLUMN email",
+				},
+				{Up: "ALTER TABLE users ADD COLUMN score bigint NOT NULL DEFAULT 0", Down: "ALTER TABLE users DROP COLUMN score"},
+				{Up: "ALTER TABLE users ADD COLUMN nickname text", Down: "ALTER TABLE users DROP COLUMN nickname"},
+				{Up: "ALTER TABLE users DROP COLUMN legacy", Down: "ALTER TABLE users ADD COLUMN legacy text"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		changes := Diff(table, c.columns, c.indexes, c.opts...)
+		if !reflect.DeepEqual(changes, c.expected) {
+			t.Fatalf("%s: expected\n%q\ngot\n%q", c.name, c.expected, changes)
+		}
+	}
+}
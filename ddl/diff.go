@@ -0,0 +1,140 @@
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/olegfomenko/pg-dao/introspect"
+)
+
+// Change is a schema change together with the statement reverting it.
+type Change struct {
+	Up   string
+	Down string
+}
+
+// A DiffOption configures Diff.
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	drop bool
+}
+
+// WithDrop makes Diff drop columns missing from the table definition. Without it such columns are kept.
+func WithDrop() DiffOption {
+	return func(o *diffOptions) {
+		o.drop = true
+	}
+}
+
+// DiffSchema reads the current structure of the table and returns changes migrating it to t.
+func DiffSchema(ctx context.Context, inspector *introspect.Inspector, t Table, opts ...DiffOption) ([]Change, error) {
+	columns, err := inspector.Columns(ctx, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := inspector.Indexes(ctx, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(t, columns, indexes, opts...), nil
+}
+
+// Diff returns changes migrating the table with current columns and indexes to t. If there are no current
+// columns the table is created. Type modifiers such as varchar length are not compared, neither are
+// primary keys and non-empty defaults. NOT NULL columns without a default are added nullable with
+// a comment, as existing rows have no values for them.
+func Diff(t Table, columns []introspect.Column, indexes []introspect.Index, opts ...DiffOption) []Change {
+	o := diffOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(columns) == 0 {
+		return []Change{{Up: strings.Join(t.Create(), ";\n"), Down: t.Drop()}}
+	}
+
+	current := make(map[string]introspect.Column, len(columns))
+	for _, c := range columns {
+		current[c.Name] = c
+	}
+
+	var changes []Change
+	alter := func(up, down string) {
+		changes = append(changes, Change{
+			Up:   fmt.Sprintf("ALTER TABLE %s %s", t.Name, up),
+			Down: fmt.Sprintf("ALTER TABLE %s %s", t.Name, down),
+		})
+	}
+
+	desired := make(map[string]bool, len(t.Columns))
+	for _, c := range t.Columns {
+		desired[c.Name] = true
+		cur, ok := current[c.Name]
+		if !ok {
+			// existing rows would violate NOT NULL without a default, so the column is added nullable
+			if c.NotNull && c.Default == "" && !c.PrimaryKey && serialTypes[strings.ToLower(c.Type)] == "" {
+				c.NotNull = false
+				changes = append(changes, Change{
+					Up: fmt.Sprintf("-- %s is added nullable: fill it for existing rows, then SET NOT NULL\nALTER TABLE %s ADD COLUMN %s",
+						c.Name, t.Name, c.definition()),
+					Down: fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", t.Name, c.Name),
+				})
+				continue
+			}
+			alter("ADD COLUMN "+c.definition(), "DROP COLUMN "+c.Name)
+			continue
+		}
+
+		if udt, _ := introspect.NormalizeType(c.Type); udt != cur.UDTName {
+			typ := c.Type
+			if base, ok := serialTypes[strings.ToLower(typ)]; ok {
+				typ = base
+			}
+			alter(
+				fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s::%s", c.Name, typ, c.Name, typ),
+				fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s::%s", c.Name, cur.SQLType(), c.Name, cur.SQLType()),
+			)
+		}
+
+		switch {
+		case c.NotNull && cur.Nullable:
+			alter("ALTER COLUMN "+c.Name+" SET NOT NULL", "ALTER COLUMN "+c.Name+" DROP NOT NULL")
+		case !c.NotNull && !cur.Nullable:
+			alter("ALTER COLUMN "+c.Name+" DROP NOT NULL", "ALTER COLUMN "+c.Name+" SET NOT NULL")
+		}
+
+		switch {
+		case serialTypes[strings.ToLower(c.Type)] != "" || cur.Identity || cur.Generated:
+		case c.Default == "" && cur.Default != nil:
+			alter("ALTER COLUMN "+c.Name+" DROP DEFAULT", "ALTER COLUMN "+c.Name+" SET DEFAULT "+*cur.Default)
+		case c.Default != "" && cur.Default == nil:
+			alter("ALTER COLUMN "+c.Name+" SET DEFAULT "+c.Default, "ALTER COLUMN "+c.Name+" DROP DEFAULT")
+		}
+	}
+
+	if o.drop {
+		for _, cur := range columns {
+			if desired[cur.Name] {
+				continue
+			}
+			def := Column{Name: cur.Name, Type: cur.SQLType(), NotNull: !cur.Nullable}
+			if cur.Default != nil {
+				def.Default = *cur.Default
+			}
+			alter("DROP COLUMN "+cur.Name, "ADD COLUMN "+def.definition())
+		}
+	}
+
+	existing := make(map[string]bool, len(indexes))
+	for _, idx := range indexes {
+		existing[idx.Name] = true
+	}
+	for _, idx := range t.Indexes {
+		if !existing[idx.Name] {
+			changes = append(changes, Change{Up: idx.create(t.Name), Down: "DROP INDEX " + idx.Name})
+		}
+	}
+	return changes
+}
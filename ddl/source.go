@@ -0,0 +1,115 @@
+package ddl
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// FromSource builds the table definition from the struct declared in Go files of the directory.
+// Types are resolved by name only, so fields of types declared elsewhere need an explicit pg:"type=..."
+// unless they have a predefined mapping. Embedded structs have to be declared in the same directory.
+func FromSource(dir, typeName, table string) (Table, error) {
+	structs, err := parseStructs(dir)
+	if err != nil {
+		return Table{}, err
+	}
+
+	st, ok := structs[typeName]
+	if !ok {
+		return Table{}, errors.From(errors.New("struct not found"), map[string]interface{}{"dir": dir, "type": typeName})
+	}
+
+	fields, err := sourceFields(structs, st)
+	if err != nil {
+		return Table{}, errors.Wrap(err, "failed to read struct fields", map[string]interface{}{"type": typeName})
+	}
+	return FromFields(table, fields)
+}
+
+func parseStructs(dir string) (map[string]*ast.StructType, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list go files")
+	}
+
+	fset := token.NewFileSet()
+	structs := make(map[string]*ast.StructType)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse go file", map[string]interface{}{"file": file})
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				if st, ok := spec.Type.(*ast.StructType); ok {
+					structs[spec.Name.Name] = st
+				}
+			}
+			return true
+		})
+	}
+	return structs, nil
+}
+
+func sourceFields(structs map[string]*ast.StructType, st *ast.StructType) ([]Field, error) {
+	var fields []Field
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			unquoted, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid struct tag")
+			}
+			tag = reflect.StructTag(unquoted)
+		}
+
+		name := columnName(tag)
+		if name == "-" {
+			continue
+		}
+
+		typ := types.ExprString(f.Type)
+		if len(f.Names) == 0 {
+			if name != "" {
+				fields = append(fields, Field{Name: typ, Type: typ, Tag: tag})
+				continue
+			}
+			embedded, ok := structs[strings.TrimLeft(typ, "*")]
+			if !ok {
+				return nil, errors.From(errors.New("embedded struct not found"), map[string]interface{}{"type": typ})
+			}
+			nested, err := sourceFields(structs, embedded)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+
+		for _, ident := range f.Names {
+			if ident.IsExported() {
+				fields = append(fields, Field{Name: ident.Name, Type: typ, Tag: tag, kind: sourceKind(structs, typ)})
+			}
+		}
+	}
+	return fields, nil
+}
+
+// sourceKind returns kind of types declared in the same directory, nested struct types are stored as jsonb.
+func sourceKind(structs map[string]*ast.StructType, typ string) reflect.Kind {
+	if _, ok := structs[strings.TrimLeft(typ, "*")]; ok {
+		return reflect.Struct
+	}
+	return reflect.Invalid
+}
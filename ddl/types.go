@@ -0,0 +1,128 @@
+package ddl
+
+import (
+	"reflect"
+	"strings"
+)
+
+var sqlTypes = map[string]string{
+	"int":             "bigint",
+	"int64":           "bigint",
+	"uint":            "bigint",
+	"uint64":          "bigint",
+	"uint32":          "bigint",
+	"int32":           "integer",
+	"uint16":          "integer",
+	"int16":           "smallint",
+	"int8":            "smallint",
+	"uint8":           "smallint",
+	"float64":         "double precision",
+	"float32":         "real",
+	"bool":            "boolean",
+	"string":          "text",
+	"time.Time":       "timestamp with time zone",
+	"[]byte":          "bytea",
+	"[]uint8":         "bytea",
+	"json.RawMessage": "jsonb",
+	"[]string":        "text[]",
+	"pq.StringArray":  "text[]",
+	"[]int64":         "bigint[]",
+	"pq.Int64Array":   "bigint[]",
+	"[]float64":       "double precision[]",
+	"pq.Float64Array": "double precision[]",
+	"[]bool":          "boolean[]",
+	"pq.BoolArray":    "boolean[]",
+	"sql.NullString":  "text",
+	"sql.NullInt64":   "bigint",
+	"sql.NullInt32":   "integer",
+	"sql.NullFloat64": "double precision",
+	"sql.NullBool":    "boolean",
+	"sql.NullTime":    "timestamp with time zone",
+	"pq.NullTime":     "timestamp with time zone",
+	"pg.JSON":         "jsonb",
+	"pg.Hstore":       "hstore",
+	"pg.Int8Range":    "int8range",
+	"pg.TstzRange":    "tstzrange",
+}
+
+// serialTypes maps serial types to their underlying integer types.
+var serialTypes = map[string]string{
+	"smallserial": "smallint",
+	"serial":      "integer",
+	"bigserial":   "bigint",
+}
+
+var kindTypes = map[reflect.Kind]string{
+	reflect.Bool:    "boolean",
+	reflect.Int:     "bigint",
+	reflect.Int8:    "smallint",
+	reflect.Int16:   "smallint",
+	reflect.Int32:   "integer",
+	reflect.Int64:   "bigint",
+	reflect.Uint8:   "smallint",
+	reflect.Uint16:  "integer",
+	reflect.Uint32:  "bigint",
+	reflect.Uint64:  "bigint",
+	reflect.Float32: "real",
+	reflect.Float64: "double precision",
+	reflect.String:  "text",
+	reflect.Struct:  "jsonb",
+	reflect.Map:     "jsonb",
+}
+
+// basicKinds maps names of predeclared types to their kinds, e.g. for slices declared in source.
+var basicKinds = make(map[string]reflect.Kind)
+
+func init() {
+	for kind := range kindTypes {
+		basicKinds[kind.String()] = kind
+	}
+}
+
+// sqlType returns the column type for the Go type of kind, elem is the kind of slice elements if known.
+// Maps and unknown structs are stored as jsonb, slices of strings, numbers and booleans as arrays
+// the same way DAO writes them.
+func sqlType(goType string, kind, elem reflect.Kind) (string, bool) {
+	goType = strings.Replace(strings.TrimLeft(goType, "*"), "pg_dao.", "pg.", 1)
+	if typ, ok := sqlTypes[goType]; ok {
+		return typ, true
+	}
+	if strings.HasPrefix(goType, "map[") {
+		return "jsonb", true
+	}
+	if kind == reflect.Slice || strings.HasPrefix(goType, "[]") {
+		if elem == reflect.Invalid {
+			elem = basicKinds[strings.TrimPrefix(goType, "[]")]
+		}
+		return arrayType(elem)
+	}
+	typ, ok := kindTypes[kind]
+	return typ, ok
+}
+
+// arrayType returns the array type of slices of elem, []byte is stored as bytea.
+func arrayType(elem reflect.Kind) (string, bool) {
+	switch elem {
+	case reflect.Uint8:
+		return "bytea", true
+	case reflect.Struct, reflect.Map, reflect.Invalid:
+		return "", false
+	}
+	typ, ok := kindTypes[elem]
+	if !ok {
+		return "", false
+	}
+	return typ + "[]", true
+}
+
+// nullable reports whether the Go type can hold NULL.
+func nullable(goType string, kind reflect.Kind) bool {
+	switch {
+	case strings.HasPrefix(goType, "*"), strings.HasPrefix(goType, "[]"), strings.HasPrefix(goType, "map["),
+		strings.HasPrefix(goType, "sql.Null"), goType == "pq.NullTime", goType == "json.RawMessage",
+		strings.HasPrefix(goType, "pq.") && strings.HasSuffix(goType, "Array"),
+		strings.HasSuffix(goType, ".JSON"), strings.HasSuffix(goType, ".Hstore"):
+		return true
+	}
+	return kind == reflect.Map || kind == reflect.Slice
+}
@@ -0,0 +1,64 @@
+package pg_dao
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// DryRunStatement is returned as an error by Create, Update and Delete of a dry-run session
+// instead of executing the statement. SQL is rebound to $n placeholders the same way pgdb does.
+type DryRunStatement struct {
+	SQL  string
+	Args []interface{}
+}
+
+func (s *DryRunStatement) Error() string {
+	return "dry run: " + s.SQL
+}
+
+func newDryRunStatement(stmt sq.Sqlizer) error {
+	query, args, err := toSQL(stmt)
+	if err != nil {
+		return err
+	}
+	return &DryRunStatement{
+		SQL:  query,
+		Args: args,
+	}
+}
+
+// DryRun switches the session to dry-run mode: Create, Update and Delete return *DryRunStatement
+// as an error instead of executing, their hooks are not run. Reads are executed as usual.
+// Sessions created with New() keep the mode.
+func (d *dao) DryRun() DAO {
+	d.dryRun = true
+	return d
+}
+
+// ToSQL returns the pending update if any update clause has been added, otherwise the pending delete
+// if any delete condition has been added, otherwise the pending select.
+func (d *dao) ToSQL() (query string, args []interface{}, err error) {
+	switch {
+	case d.err != nil:
+		return "", nil, d.err
+	case d.updSet || len(d.updWhere) > 0 || d.version != nil:
+		upd, _, err := d.buildUpdate(false)
+		if err != nil {
+			return "", nil, err
+		}
+		return toSQL(upd)
+	case len(d.dltWhere) > 0:
+		return toSQL(d.dlt)
+	default:
+		return toSQL(d.sql)
+	}
+}
+
+func toSQL(stmt sq.Sqlizer) (string, []interface{}, error) {
+	query, args, err := stmt.ToSql()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to build query")
+	}
+	return sqlx.Rebind(sqlx.DOLLAR, query), args, nil
+}
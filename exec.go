@@ -0,0 +1,106 @@
+package pg_dao
+
+import (
+	"context"
+	"database/sql"
+	goerr "errors"
+	"reflect"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"gitlab.com/distributed_lab/logan/v3"
+)
+
+// get, query and exec are the only places statements built by dao are executed,
+// so every statement is instrumented the same way.
+
+func (d *dao) get(ctx context.Context, dest interface{}, stmt sq.Sqlizer) error {
+	return d.run(ctx, stmt, func(ctx context.Context, query string, args []interface{}) (int64, error) {
+		scan, done := scanDest(dest)
+		if err := d.executor.GetContext(ctx, scan, query, args...); err != nil {
+			return 0, err
+		}
+		if err := done(); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
+}
+
+func (d *dao) query(ctx context.Context, dest interface{}, stmt sq.Sqlizer) error {
+	return d.run(ctx, stmt, func(ctx context.Context, query string, args []interface{}) (int64, error) {
+		scan, done := scanDest(dest)
+		if err := d.executor.SelectContext(ctx, scan, query, args...); err != nil {
+			return 0, err
+		}
+		if err := done(); err != nil {
+			return 0, err
+		}
+		if list := reflect.ValueOf(dest).Elem(); list.Kind() == reflect.Slice {
+			return int64(list.Len()), nil
+		}
+		return 0, nil
+	})
+}
+
+func (d *dao) exec(ctx context.Context, stmt sq.Sqlizer) (res sql.Result, err error) {
+	err = d.run(ctx, stmt, func(ctx context.Context, query string, args []interface{}) (int64, error) {
+		res, err = d.executor.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	})
+	return res, err
+}
+
+func (d *dao) run(ctx context.Context, stmt sq.Sqlizer, fn func(ctx context.Context, query string, args []interface{}) (int64, error)) (err error) {
+	query, args, err := toSQL(stmt)
+	if err != nil {
+		return err
+	}
+
+	var span Span
+	if d.opts.tracer != nil {
+		ctx, span = d.startSpan(ctx, query)
+		defer func() {
+			span.End(err)
+		}()
+	}
+	if d.opts.commenter {
+		query += sqlComment(ctx, span)
+	}
+
+	start := time.Now()
+	rows, err := fn(ctx, query, args)
+	duration := time.Since(start)
+	d.logStatement(query, args, duration, rows, err)
+	d.checkSlow(ctx, query, args, duration)
+
+	return err
+}
+
+func (d *dao) logStatement(query string, args []interface{}, duration time.Duration, rows int64, err error) {
+	if d.opts.log == nil {
+		return
+	}
+	if goerr.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+
+	fields := logan.F{
+		"table":         d.tableName,
+		"sql":           query,
+		"args_count":    len(args),
+		"duration":      duration,
+		"rows_affected": rows,
+	}
+	if d.opts.logArgs {
+		if d.opts.redactArgs != nil {
+			args = d.opts.redactArgs(query, args)
+		}
+		fields["args"] = args
+	}
+
+	d.opts.log.Log(uint32(d.opts.logLevel), fields, err, false, "sql statement executed")
+}
@@ -0,0 +1,47 @@
+package pg_dao
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+// An Executor runs statements built by DAO. Queries are passed with $n placeholders.
+// By default statements are executed with the *pgdb.DB passed to NewDAO.
+type Executor interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	TransactionWithOptions(opts *sql.TxOptions, fn func() error) error
+}
+
+// WithExecutor makes DAO run statements with the provided Executor instead of the database.
+// The database passed to NewDAO may be nil in this case, then ExecRaw receives nil
+// and slow queries are not explained.
+func WithExecutor(executor Executor) Option {
+	return func(o *options) {
+		o.executor = executor
+	}
+}
+
+type dbExecutor struct {
+	db *pgdb.DB
+}
+
+func (e dbExecutor) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return e.db.GetRawContext(ctx, dest, query, args...)
+}
+
+func (e dbExecutor) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return e.db.SelectRawContext(ctx, dest, query, args...)
+}
+
+func (e dbExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return e.db.ExecWithResultContext(ctx, sq.Expr(query, args...))
+}
+
+func (e dbExecutor) TransactionWithOptions(opts *sql.TxOptions, fn func() error) error {
+	return e.db.TransactionWithOptions(opts, fn)
+}
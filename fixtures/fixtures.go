@@ -0,0 +1,534 @@
+// Package fixtures loads repeatable data sets from YAML or JSON files into the database.
+//
+// Every file maps table names to named rows:
+//
+//	authors:
+//	  alice:
+//	    name: Alice
+//	books:
+//	  dune:
+//	    title: Dune
+//	    author_id: $authors.alice
+//
+// A string value "$table.name", where table consists of lower-case letters, digits and underscores
+// and name of letters, digits and underscores, is replaced with the id of the referenced row.
+// Tables are loaded so that referenced rows are inserted first. Other strings are stored as is,
+// except that a leading "$$" is replaced with "$", so "$$authors.alice" stores "$authors.alice".
+// Lists of strings, numbers or booleans are stored as arrays, other lists and nested maps as JSON.
+//
+// Rows are inserted with DAO.BulkCreate, so hooks, timestamps and audit configured with WithDAOOptions
+// are applied. Tables without an id column, e.g. join tables, can not be created by DAO and are inserted
+// with plain INSERT statements; their rows can not be referenced.
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	pg "github.com/olegfomenko/pg-dao"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Refs maps "table.name" of every loaded row to its id.
+type Refs map[string]int64
+
+// An Option configures fixtures loading.
+type Option func(*loader)
+
+// WithTruncate truncates all tables present in the fixtures before loading, restarting their identities.
+// Truncation fails if other tables reference them, see WithTruncateCascade.
+func WithTruncate() Option {
+	return func(l *loader) {
+		l.truncate = true
+	}
+}
+
+// WithTruncateCascade is WithTruncate that also truncates tables referencing the loaded ones,
+// even if they have no fixtures.
+func WithTruncateCascade() Option {
+	return func(l *loader) {
+		l.truncate = true
+		l.cascade = true
+	}
+}
+
+// WithDAOOptions sets options of the DAO rows of table are created with, e.g. pg.WithTimestamps,
+// pg.WithHooks or pg.WithAudit. pg.WithinTransaction is added as all rows are loaded in one transaction.
+func WithDAOOptions(table string, opts ...pg.Option) Option {
+	return func(l *loader) {
+		l.daoOpts[table] = append(l.daoOpts[table], opts...)
+	}
+}
+
+type fixture struct {
+	name string
+	row  map[string]interface{}
+}
+
+type loader struct {
+	db       *pgdb.DB
+	truncate bool
+	cascade  bool
+	daoOpts  map[string][]pg.Option
+	// tables keeps rows of every table in file order.
+	tables map[string][]fixture
+	refs   Refs
+}
+
+// Load loads all *.yml, *.yaml and *.json files from the root of fsys in a single transaction.
+// Rows of every table are inserted with a single statement where possible, id sequences of loaded
+// tables are reset afterwards so rows with explicit ids do not break subsequent inserts.
+func Load(ctx context.Context, db *pgdb.DB, fsys fs.FS, opts ...Option) (Refs, error) {
+	l := &loader{
+		db:      db,
+		daoOpts: make(map[string][]pg.Option),
+		tables:  make(map[string][]fixture),
+		refs:    make(Refs),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if err := l.read(fsys); err != nil {
+		return nil, err
+	}
+	order, err := l.order()
+	if err != nil {
+		return nil, err
+	}
+	if len(order) == 0 {
+		return l.refs, nil
+	}
+
+	err = db.Transaction(func() error {
+		if l.truncate {
+			names := make([]string, len(order))
+			for i, table := range order {
+				names[i] = quoteIdent(table)
+			}
+			stmt := "TRUNCATE " + strings.Join(names, ", ") + " RESTART IDENTITY"
+			if l.cascade {
+				stmt += " CASCADE"
+			}
+			if err := db.ExecRawContext(ctx, stmt); err != nil {
+				return errors.Wrap(err, "failed to truncate tables")
+			}
+		}
+		for _, table := range order {
+			if err := l.load(ctx, table); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l.refs, nil
+}
+
+func (l *loader) read(fsys fs.FS) error {
+	var files []string
+	for _, pattern := range []string{"*.yml", "*.yaml", "*.json"} {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return errors.Wrap(err, "failed to list fixture files")
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return errors.New("no fixture files found")
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		raw, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return errors.Wrap(err, "failed to read fixture file", map[string]interface{}{"file": file})
+		}
+
+		// JSON is a subset of YAML, so both are decoded the same way keeping keys order
+		var tables yaml.MapSlice
+		if err := yaml.Unmarshal(raw, &tables); err != nil {
+			return errors.Wrap(err, "failed to decode fixture file", map[string]interface{}{"file": path.Base(file)})
+		}
+
+		for _, table := range tables {
+			name := fmt.Sprint(table.Key)
+			rows, ok := table.Value.(yaml.MapSlice)
+			if !ok {
+				return errors.From(errors.New("table must be a map of named rows"), map[string]interface{}{
+					"file":  file,
+					"table": name,
+				})
+			}
+			for _, r := range rows {
+				values, ok := r.Value.(yaml.MapSlice)
+				if !ok {
+					return errors.From(errors.New("row must be a map of columns"), map[string]interface{}{
+						"file": file,
+						"row":  name + "." + fmt.Sprint(r.Key),
+					})
+				}
+				row, err := decodeRow(values)
+				if err != nil {
+					return errors.Wrap(err, "failed to decode row", map[string]interface{}{
+						"file": file,
+						"row":  name + "." + fmt.Sprint(r.Key),
+					})
+				}
+				l.tables[name] = append(l.tables[name], fixture{name: fmt.Sprint(r.Key), row: row})
+			}
+		}
+	}
+	return nil
+}
+
+// order returns table names sorted so that every table goes after the tables it references.
+func (l *loader) order() ([]string, error) {
+	names := make([]string, 0, len(l.tables))
+	for name := range l.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(names))
+	order := make([]string, 0, len(names))
+
+	var visit func(table string) error
+	visit = func(table string) error {
+		switch state[table] {
+		case visited:
+			return nil
+		case visiting:
+			return errors.From(errors.New("cyclic references between tables"), map[string]interface{}{"table": table})
+		}
+		state[table] = visiting
+
+		for _, dep := range l.dependencies(table) {
+			if _, ok := l.tables[dep]; !ok {
+				return errors.From(errors.New("referenced table has no fixtures"), map[string]interface{}{
+					"table":      table,
+					"referenced": dep,
+				})
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		state[table] = visited
+		order = append(order, table)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// dependencies returns sorted names of other tables referenced by rows of table.
+func (l *loader) dependencies(table string) []string {
+	deps := make(map[string]bool)
+	for _, f := range l.tables[table] {
+		for _, val := range f.row {
+			if ref, ok := reference(val); ok {
+				if dep := strings.SplitN(ref, ".", 2)[0]; dep != table {
+					deps[dep] = true
+				}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(deps))
+	for dep := range deps {
+		result = append(result, dep)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// load inserts rows of table in file order with multi-row INSERT statements. A row referencing
+// a row of the same table that is not inserted yet starts a new statement.
+func (l *loader) load(ctx context.Context, table string) error {
+	hasID, err := l.hasID(ctx, table)
+	if err != nil {
+		return err
+	}
+	if !hasID {
+		return l.insertPlain(ctx, table, l.tables[table])
+	}
+
+	q := pg.NewDAO(l.db, table, append(l.daoOpts[table], pg.WithinTransaction())...)
+	var batch []fixture
+	pending := make(map[string]bool)
+	for _, f := range l.tables[table] {
+		for _, val := range f.row {
+			if ref, ok := reference(val); ok && pending[ref] {
+				if err := l.insert(ctx, q, table, batch); err != nil {
+					return err
+				}
+				batch, pending = nil, make(map[string]bool)
+				break
+			}
+		}
+		batch = append(batch, f)
+		pending[table+"."+f.name] = true
+	}
+	if err := l.insert(ctx, q, table, batch); err != nil {
+		return err
+	}
+	return l.resetSequence(ctx, table)
+}
+
+// hasID reports whether table has the id column DAO returns on create.
+func (l *loader) hasID(ctx context.Context, table string) (bool, error) {
+	var exists bool
+	stmt := sq.Expr(
+		"SELECT EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = ?::regclass AND attname = ? AND NOT attisdropped)",
+		quoteIdent(table), pg.IdColumn,
+	)
+	if err := l.db.GetContext(ctx, &exists, stmt); err != nil {
+		return false, errors.Wrap(err, "failed to check id column", map[string]interface{}{"table": table})
+	}
+	return exists, nil
+}
+
+// insert creates rows with a single statement through the DAO and stores their ids.
+func (l *loader) insert(ctx context.Context, q pg.DAO, table string, rows []fixture) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	values, err := l.values(table, rows)
+	if err != nil {
+		return err
+	}
+	ids, err := q.New().BulkCreateCtx(ctx, values)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert fixtures", map[string]interface{}{
+			"table": table,
+			"rows":  len(rows),
+		})
+	}
+	for i, f := range rows {
+		l.refs[table+"."+f.name] = ids[i]
+	}
+	return nil
+}
+
+// insertPlain inserts rows of a table without id column with a single statement,
+// columns missing in a row are set to DEFAULT.
+func (l *loader) insertPlain(ctx context.Context, table string, rows []fixture) error {
+	values, err := l.values(table, rows)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range values {
+		for col := range row {
+			if !seen[col] {
+				seen[col] = true
+				columns = append(columns, col)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return errors.From(errors.New("rows of table without id column have no values"), map[string]interface{}{
+			"table": table,
+		})
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = pq.QuoteIdentifier(col)
+	}
+	stmt := sq.Insert(quoteIdent(table)).Columns(quoted...)
+	for _, row := range values {
+		args := make([]interface{}, len(columns))
+		for i, col := range columns {
+			val, ok := row[col]
+			if !ok {
+				val = sq.Expr("DEFAULT")
+			}
+			args[i] = val
+		}
+		stmt = stmt.Values(args...)
+	}
+	if err := l.db.ExecContext(ctx, stmt); err != nil {
+		return errors.Wrap(err, "failed to insert fixtures", map[string]interface{}{
+			"table": table,
+			"rows":  len(rows),
+		})
+	}
+	return nil
+}
+
+// values returns column values of rows with references resolved to ids.
+func (l *loader) values(table string, rows []fixture) ([]map[string]interface{}, error) {
+	values := make([]map[string]interface{}, len(rows))
+	for i, f := range rows {
+		values[i] = make(map[string]interface{}, len(f.row))
+		for col, val := range f.row {
+			if ref, ok := reference(val); ok {
+				id, ok := l.refs[ref]
+				if !ok {
+					return nil, errors.From(errors.New("unresolved reference"), map[string]interface{}{
+						"row":       table + "." + f.name,
+						"reference": ref,
+					})
+				}
+				val = id
+			}
+			if s, ok := val.(string); ok && strings.HasPrefix(s, "$$") {
+				val = s[1:]
+			}
+			values[i][col] = val
+		}
+	}
+	return values, nil
+}
+
+// resetSequence moves the sequence of the id column, if any, past the ids of loaded rows.
+func (l *loader) resetSequence(ctx context.Context, table string) error {
+	id := pq.QuoteIdentifier(pg.IdColumn)
+	stmt := sq.Expr(
+		"SELECT setval(pg_get_serial_sequence(?, ?), max("+id+")) FROM "+quoteIdent(table)+
+			" HAVING max("+id+") IS NOT NULL AND pg_get_serial_sequence(?, ?) IS NOT NULL",
+		quoteIdent(table), pg.IdColumn, quoteIdent(table), pg.IdColumn,
+	)
+	if err := l.db.ExecContext(ctx, stmt); err != nil {
+		return errors.Wrap(err, "failed to reset sequence", map[string]interface{}{"table": table})
+	}
+	return nil
+}
+
+// quoteIdent quotes the table name, which may be qualified with a schema.
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+var referenceRe = regexp.MustCompile(`^\$[a-z_][a-z0-9_]*\.[A-Za-z0-9_]+$`)
+
+// reference returns "table.name" if val is a reference to another row.
+func reference(val interface{}) (string, bool) {
+	s, ok := val.(string)
+	if !ok || !referenceRe.MatchString(s) {
+		return "", false
+	}
+	return s[1:], true
+}
+
+func decodeRow(values yaml.MapSlice) (map[string]interface{}, error) {
+	row := make(map[string]interface{}, len(values))
+	for _, item := range values {
+		val := plain(item.Value)
+		switch v := val.(type) {
+		case []interface{}:
+			if list, ok := scalarList(v); ok {
+				val = pg.Array(list)
+				break
+			}
+			val = pg.JSON{V: v}
+		case map[string]interface{}:
+			val = pg.JSON{V: v}
+		}
+		row[fmt.Sprint(item.Key)] = val
+	}
+	return row, nil
+}
+
+// scalarList converts a list of strings, integers, numbers or booleans to a slice of their type.
+// Integers are converted to float64 if the list contains other numbers.
+func scalarList(list []interface{}) (interface{}, bool) {
+	var (
+		strs   []string
+		ints   []int64
+		floats []float64
+		bools  []bool
+	)
+	for _, item := range list {
+		switch v := item.(type) {
+		case string:
+			strs = append(strs, v)
+		case int:
+			ints = append(ints, int64(v))
+			floats = append(floats, float64(v))
+		case int64:
+			ints = append(ints, v)
+			floats = append(floats, float64(v))
+		case uint64:
+			ints = append(ints, int64(v))
+			floats = append(floats, float64(v))
+		case float64:
+			floats = append(floats, v)
+		case bool:
+			bools = append(bools, v)
+		default:
+			return nil, false
+		}
+	}
+
+	switch len(list) {
+	case 0:
+		return []string{}, true
+	case len(strs):
+		return strs, true
+	case len(ints):
+		return ints, true
+	case len(floats):
+		return floats, true
+	case len(bools):
+		return bools, true
+	}
+	return nil, false
+}
+
+// plain converts decoded YAML values to types encoding/json can marshal.
+func plain(val interface{}) interface{} {
+	switch v := val.(type) {
+	case yaml.MapSlice:
+		m := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			m[fmt.Sprint(item.Key)] = plain(item.Value)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = plain(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = plain(item)
+		}
+		return list
+	}
+	return val
+}
@@ -0,0 +1,139 @@
+package fixtures
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOrder(t *testing.T) {
+	cases := []struct {
+		name     string
+		files    map[string]string
+		expected []string
+		fails    bool
+	}{
+		{
+			name: "references first",
+			files: map[string]string{
+				"a.yml": "books:\n  dune:\n    author_id: $authors.alice\n    genre_id: $genres.scifi\n",
+				"b.yml": "genres:\n  scifi:\n    name: scifi\nauthors:\n  alice:\n    name: Alice\n",
+			},
+			expected: []string{"authors", "genres", "books"},
+		},
+		{
+			name: "chain across files",
+			files: map[string]string{
+				"a.json": `{"reviews": {"r1": {"book_id": "$books.dune"}}}`,
+				"b.yml":  "books:\n  dune:\n    author_id: $authors.alice\nauthors:\n  alice:\n    name: Alice\n",
+			},
+			expected: []string{"authors", "books", "reviews"},
+		},
+		{
+			name: "self reference",
+			files: map[string]string{
+				"a.yml": "users:\n  root:\n    name: root\n  alice:\n    parent_id: $users.root\n",
+			},
+			expected: []string{"users"},
+		},
+		{
+			name: "literal strings",
+			files: map[string]string{
+				"a.yml": "prices:\n  p1:\n    amount: $5.00\n    note: $$books.dune\n    tag: $Books.dune\n",
+			},
+			expected: []string{"prices"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"a.yml": "a:\n  x:\n    b_id: $b.y\nb:\n  y:\n    a_id: $a.x\n",
+			},
+			fails: true,
+		},
+		{
+			name: "missing table",
+			files: map[string]string{
+				"a.yml": "books:\n  dune:\n    author_id: $authors.alice\n",
+			},
+			fails: true,
+		},
+	}
+
+	for _, c := range cases {
+		fsys := make(fstest.MapFS, len(c.files))
+		for name, data := range c.files {
+			fsys[name] = &fstest.MapFile{Data: []byte(data)}
+		}
+
+		l := &loader{tables: make(map[string][]fixture), refs: make(Refs)}
+		if err := l.read(fsys); err != nil {
+			t.Fatalf("%s: read: %v", c.name, err)
+		}
+		order, err := l.order()
+		if c.fails {
+			if err == nil {
+				t.Fatalf("%s: expected error, got order %v", c.name, order)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: order: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(order, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, order)
+		}
+	}
+}
+
+func TestReference(t *testing.T) {
+	cases := []struct {
+		val      interface{}
+		expected string
+		ok       bool
+	}{
+		{val: "$authors.alice", expected: "authors.alice", ok: true},
+		{val: "$book_tags.Tag_1", expected: "book_tags.Tag_1", ok: true},
+		{val: "$$authors.alice"},
+		{val: "$5.00"},
+		{val: "$Authors.alice"},
+		{val: "$authors.alice smith"},
+		{val: "$public.authors.alice"},
+		{val: "authors.alice"},
+		{val: 42},
+	}
+
+	for _, c := range cases {
+		ref, ok := reference(c.val)
+		if ok != c.ok || ref != c.expected {
+			t.Fatalf("%v: expected %q %v, got %q %v", c.val, c.expected, c.ok, ref, ok)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	l := &loader{refs: Refs{"authors.alice": 7}}
+	values, err := l.values("books", []fixture{{
+		name: "dune",
+		row: map[string]interface{}{
+			"author_id": "$authors.alice",
+			"note":      "$$authors.alice",
+			"price":     "$5.00",
+		},
+	}})
+	if err != nil {
+		t.Fatalf("values: %v", err)
+	}
+
+	expected := []map[string]interface{}{{
+		"author_id": int64(7),
+		"note":      "$authors.alice",
+		"price":     "$5.00",
+	}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+
+	if _, err := l.values("books", []fixture{{name: "x", row: map[string]interface{}{"a": "$authors.bob"}}}); err == nil {
+		t.Fatalf("expected unresolved reference error")
+	}
+}
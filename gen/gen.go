@@ -0,0 +1,330 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/olegfomenko/pg-dao/introspect"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+const (
+	importContext = "context"
+	importTime    = "time"
+	importPQ      = "github.com/lib/pq"
+	importPG      = "github.com/olegfomenko/pg-dao"
+	importPGDB    = "gitlab.com/distributed_lab/kit/pgdb"
+)
+
+// initialisms are kept upper-cased in generated names, as golint expects.
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "JSON": true, "UUID": true, "API": true, "HTTP": true,
+	"IP": true, "SQL": true, "HTML": true, "XML": true, "TTL": true,
+}
+
+var goTypes = map[string]string{
+	"int2":        "int16",
+	"int4":        "int32",
+	"int8":        "int64",
+	"oid":         "uint32",
+	"float4":      "float32",
+	"float8":      "float64",
+	"numeric":     "string",
+	"bool":        "bool",
+	"bytea":       "[]byte",
+	"hstore":      "pg.Hstore",
+	"int8range":   "pg.Int8Range",
+	"tstzrange":   "pg.TstzRange",
+	"timestamp":   "time.Time",
+	"timestamptz": "time.Time",
+	"date":        "time.Time",
+	"time":        "time.Time",
+	"timetz":      "time.Time",
+}
+
+var arrayTypes = map[string]string{
+	"int2":   "pq.Int64Array",
+	"int4":   "pq.Int64Array",
+	"int8":   "pq.Int64Array",
+	"float4": "pq.Float64Array",
+	"float8": "pq.Float64Array",
+	"bool":   "pq.BoolArray",
+	"bytea":  "pq.ByteaArray",
+}
+
+type file struct {
+	Package    string
+	StdImports []string
+	Imports    []string
+	Models     []model
+}
+
+type model struct {
+	Table   string
+	Name    string
+	Fields  []field
+	Filters []filter
+}
+
+type field struct {
+	Name   string
+	Column string
+	Type   string
+	Tag    string
+}
+
+type filter struct {
+	Name   string
+	Fields []field
+}
+
+// Generate returns formatted Go source of package pkg with a DTO struct, column constants and a typed DAO
+// for every table.
+func Generate(pkg string, tables []Table) ([]byte, error) {
+	f := file{Package: pkg}
+	imports := map[string]bool{importContext: true, importPG: true, importPGDB: true}
+
+	for _, t := range tables {
+		m := model{Table: t.Name, Name: camel(singular(t.Name))}
+		byColumn := make(map[string]field, len(t.Columns))
+		for _, c := range t.Columns {
+			typ, imp := goType(c)
+			if imp != "" {
+				imports[imp] = true
+			}
+			fl := field{Name: camel(c.Name), Column: c.Name, Type: typ, Tag: tag(c)}
+			m.Fields = append(m.Fields, fl)
+			byColumn[c.Name] = fl
+		}
+		m.Filters = filters(t, byColumn)
+		f.Models = append(f.Models, m)
+	}
+
+	for imp := range imports {
+		if strings.Contains(strings.Split(imp, "/")[0], ".") {
+			f.Imports = append(f.Imports, imp)
+		} else {
+			f.StdImports = append(f.StdImports, imp)
+		}
+	}
+	sort.Strings(f.StdImports)
+	sort.Strings(f.Imports)
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, f); err != nil {
+		return nil, errors.Wrap(err, "failed to execute template")
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to format generated code")
+	}
+	return src, nil
+}
+
+// filters returns FilterBy methods for unique indexes. Primary key on id is skipped as DAO has FilterByID.
+func filters(t Table, byColumn map[string]field) []filter {
+	var result []filter
+	seen := make(map[string]bool)
+
+	for _, idx := range t.Indexes {
+		if !idx.Unique || len(idx.Columns) == 0 {
+			continue
+		}
+		if len(idx.Columns) == 1 && idx.Columns[0] == "id" {
+			continue
+		}
+
+		var (
+			fl    filter
+			names []string
+			ok    = true
+		)
+		for _, col := range idx.Columns {
+			f, exists := byColumn[col]
+			if !exists {
+				ok = false
+				break
+			}
+			f.Type = strings.TrimPrefix(f.Type, "*")
+			fl.Fields = append(fl.Fields, f)
+			names = append(names, f.Name)
+		}
+		fl.Name = "FilterBy" + strings.Join(names, "And")
+		if !ok || seen[fl.Name] {
+			continue
+		}
+		seen[fl.Name] = true
+		result = append(result, fl)
+	}
+	return result
+}
+
+func goType(c introspect.Column) (typ, imp string) {
+	if c.IsArray() {
+		if typ, ok := arrayTypes[strings.TrimPrefix(c.UDTName, "_")]; ok {
+			return typ, importPQ
+		}
+		return "pq.StringArray", importPQ
+	}
+
+	typ, ok := goTypes[c.UDTName]
+	if !ok {
+		typ = "string"
+	}
+	if typ == "time.Time" {
+		imp = importTime
+	}
+	// columns with defaults are pointers, so that a nil field is skipped and a set zero value is written
+	if (c.Nullable || c.Default != nil && !readonly(c)) && typ != "[]byte" && typ != "pg.Hstore" {
+		typ = "*" + typ
+	}
+	return typ, imp
+}
+
+// tag returns the struct tag of the column. Columns filled by the database are read only,
+// columns with defaults are written only when set, i.e. their fields are not nil.
+func tag(c introspect.Column) string {
+	name := c.Name
+	switch {
+	case readonly(c):
+		name += ",readonly"
+	case c.Default != nil:
+		name += ",omitempty"
+	}
+	return fmt.Sprintf("`db:%q`", name)
+}
+
+// readonly reports whether the column is filled by the database and never written.
+func readonly(c introspect.Column) bool {
+	return c.Name == "id" || c.Identity || c.Generated
+}
+
+// camel converts snake_case name to an exported Go identifier.
+func camel(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if upper := strings.ToUpper(part); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	s := b.String()
+	if s == "" || unicode.IsDigit([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// singular returns a naive singular form of the table name.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"), strings.HasSuffix(name, "ches"),
+		strings.HasSuffix(name, "shes"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "ss"), strings.HasSuffix(name, "us"):
+		return name
+	case strings.HasSuffix(name, "s"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
+
+var fileTemplate = template.Must(template.New("file").Funcs(template.FuncMap{
+	"param": func(name string) string {
+		runes := []rune(name)
+		for i := range runes {
+			if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				break
+			}
+			runes[i] = unicode.ToLower(runes[i])
+		}
+		if name = string(runes); token.Lookup(name).IsKeyword() {
+			name += "Val"
+		}
+		return name
+	},
+}).Parse(`// Code generated by pg-dao-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .StdImports}}
+	"{{.}}"
+{{- end}}
+{{range .Imports}}
+	{{if eq . "github.com/olegfomenko/pg-dao"}}pg {{end}}"{{.}}"
+{{- end}}
+)
+{{range $m := .Models}}
+// {{$m.Name}}Table is the name of table {{printf "%q" $m.Table}}.
+const {{$m.Name}}Table = {{printf "%q" $m.Table}}
+
+// Columns of table {{printf "%q" $m.Table}}.
+const (
+{{- range $m.Fields}}
+	{{$m.Name}}Column{{.Name}} = {{printf "%q" .Column}}
+{{- end}}
+)
+
+// {{$m.Name}} is a row of table {{printf "%q" $m.Table}}.
+type {{$m.Name}} struct {
+{{- range $m.Fields}}
+	{{.Name}} {{.Type}} {{.Tag}}
+{{- end}}
+}
+
+// {{$m.Name}}Q is a typed DAO for table {{printf "%q" $m.Table}}.
+type {{$m.Name}}Q struct {
+	pg.DAO
+}
+
+// New{{$m.Name}}Q returns a typed DAO for table {{printf "%q" $m.Table}}.
+func New{{$m.Name}}Q(db *pgdb.DB, opts ...pg.Option) {{$m.Name}}Q {
+	return {{$m.Name}}Q{DAO: pg.NewDAO(db, {{$m.Name}}Table, opts...)}
+}
+
+// New cleans queries of the current session.
+func (q {{$m.Name}}Q) New() {{$m.Name}}Q {
+	return {{$m.Name}}Q{DAO: q.DAO.New()}
+}
+{{range $f := $m.Filters}}
+// {{$f.Name}} filters rows by the unique key ({{range $i, $fl := $f.Fields}}{{if $i}}, {{end}}{{$fl.Column}}{{end}}).
+func (q {{$m.Name}}Q) {{$f.Name}}({{range $i, $fl := $f.Fields}}{{if $i}}, {{end}}{{param $fl.Name}} {{$fl.Type}}{{end}}) {{$m.Name}}Q {
+	dao := q.DAO
+{{- range $f.Fields}}
+	dao = dao.FilterByColumn({{$m.Name}}Column{{.Name}}, {{param .Name}})
+{{- end}}
+	return {{$m.Name}}Q{DAO: dao}
+}
+{{end}}
+// Fetch returns the first row matching the filters or nil if there is none.
+func (q {{$m.Name}}Q) Fetch(ctx context.Context) (*{{$m.Name}}, error) {
+	var row {{$m.Name}}
+	ok, err := q.GetCtx(ctx, &row)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// FetchAll returns all rows matching the filters.
+func (q {{$m.Name}}Q) FetchAll(ctx context.Context) ([]{{$m.Name}}, error) {
+	var rows []{{$m.Name}}
+	err := q.SelectCtx(ctx, &rows)
+	return rows, err
+}
+{{end}}`))
@@ -0,0 +1,50 @@
+package gen
+
+import (
+	"testing"
+)
+
+func TestFieldTypesAndTags(t *testing.T) {
+	tables, err := FromSQL(`
+CREATE TABLE users (
+    id         BIGSERIAL PRIMARY KEY,
+    email      TEXT        NOT NULL,
+    active     BOOLEAN     NOT NULL DEFAULT true,
+    score      INTEGER     DEFAULT 0,
+    nickname   TEXT,
+    tags       TEXT[]      NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(tables))
+	}
+
+	cases := []struct {
+		column string
+		typ    string
+		tag    string
+	}{
+		{column: "id", typ: "int64", tag: "`db:\"id,readonly\"`"},
+		{column: "email", typ: "string", tag: "`db:\"email\"`"},
+		{column: "active", typ: "*bool", tag: "`db:\"active,omitempty\"`"},
+		{column: "score", typ: "*int32", tag: "`db:\"score,omitempty\"`"},
+		{column: "nickname", typ: "*string", tag: "`db:\"nickname\"`"},
+		{column: "tags", typ: "pq.StringArray", tag: "`db:\"tags,omitempty\"`"},
+		{column: "created_at", typ: "*time.Time", tag: "`db:\"created_at,omitempty\"`"},
+	}
+	for _, c := range cases {
+		col, ok := tables[0].Column(c.column)
+		if !ok {
+			t.Fatalf("%s: column not found", c.column)
+		}
+		if typ, _ := goType(col); typ != c.typ {
+			t.Fatalf("%s: expected type %s, got %s", c.column, c.typ, typ)
+		}
+		if tag := tag(col); tag != c.tag {
+			t.Fatalf("%s: expected tag %s, got %s", c.column, c.tag, tag)
+		}
+	}
+}
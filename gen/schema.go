@@ -0,0 +1,59 @@
+// Package gen generates DTO structs and typed DAOs from a database schema.
+package gen
+
+import (
+	"context"
+	"sort"
+
+	"github.com/olegfomenko/pg-dao/introspect"
+)
+
+// Table is a table structure code is generated for.
+type Table struct {
+	Name    string
+	Columns []introspect.Column
+	Indexes []introspect.Index
+}
+
+// Column returns the column with provided name.
+func (t Table) Column(name string) (introspect.Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return introspect.Column{}, false
+}
+
+// FromDatabase reads tables of the current schema. If names are provided only these tables are read,
+// names of tables that do not exist are skipped.
+func FromDatabase(ctx context.Context, inspector *introspect.Inspector, names ...string) ([]Table, error) {
+	if len(names) == 0 {
+		var err error
+		names, err = inspector.Tables(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tables := make([]Table, 0, len(names))
+	for _, name := range names {
+		columns, err := inspector.Columns(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		indexes, err := inspector.Indexes(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, Table{Name: name, Columns: columns, Indexes: indexes})
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	return tables, nil
+}
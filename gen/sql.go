@@ -0,0 +1,354 @@
+package gen
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/olegfomenko/pg-dao/introspect"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var (
+	createTableRe = regexp.MustCompile(`(?is)^create\s+(?:(?:temporary|temp|unlogged)\s+)?table\s+(?:if\s+not\s+exists\s+)?([\w."]+)\s*\((.*)\)[^)]*$`)
+	createIndexRe = regexp.MustCompile(`(?is)^create\s+(unique\s+)?index\s+(?:concurrently\s+)?(?:if\s+not\s+exists\s+)?([\w"]*)\s*on\s+(?:only\s+)?([\w."]+)\s*(?:using\s+\w+\s*)?\((.*)\)`)
+	alterTableRe  = regexp.MustCompile(`(?is)^alter\s+table\s+(?:if\s+exists\s+)?(?:only\s+)?([\w."]+)\s+(.*)$`)
+	dropTableRe   = regexp.MustCompile(`(?is)^drop\s+table\s+(?:if\s+exists\s+)?(.*?)(?:\s+(?:cascade|restrict))?$`)
+	addColumnRe   = regexp.MustCompile(`(?is)^add\s+(?:column\s+)?(?:if\s+not\s+exists\s+)?(.*)$`)
+	dropColumnRe  = regexp.MustCompile(`(?is)^drop\s+(?:column\s+)?(?:if\s+exists\s+)?([\w"]+)`)
+	alterColumnRe = regexp.MustCompile(`(?is)^alter\s+(?:column\s+)?([\w"]+)\s+(set|drop)\s+not\s+null$`)
+	constraintRe  = regexp.MustCompile(`(?is)^constraint\s+([\w"]+)\s+(.*)$`)
+	keyRe         = regexp.MustCompile(`(?is)^(primary\s+key|unique)\s*\((.*)\)`)
+)
+
+// columnKeywords end the type part of a column definition.
+var columnKeywords = map[string]bool{
+	"not": true, "null": true, "default": true, "primary": true, "unique": true, "references": true,
+	"check": true, "constraint": true, "generated": true, "collate": true,
+}
+
+// FromSQL builds tables from DDL scripts applied in order, e.g. up migrations. Supported statements are
+// CREATE TABLE, CREATE [UNIQUE] INDEX, ALTER TABLE ADD/DROP/ALTER COLUMN, ADD [CONSTRAINT] PRIMARY KEY/UNIQUE
+// and DROP TABLE; other statements are ignored.
+func FromSQL(scripts ...string) ([]Table, error) {
+	tables := make(map[string]*Table)
+	for _, script := range scripts {
+		for _, stmt := range splitStatements(stripComments(script)) {
+			if err := applyStatement(tables, stmt); err != nil {
+				return nil, errors.Wrap(err, "failed to parse statement", map[string]interface{}{"statement": stmt})
+			}
+		}
+	}
+
+	result := make([]Table, 0, len(tables))
+	for _, t := range tables {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func applyStatement(tables map[string]*Table, stmt string) error {
+	if m := createTableRe.FindStringSubmatch(stmt); m != nil {
+		t := &Table{Name: ident(m[1])}
+		for _, item := range splitTopLevel(m[2], ',') {
+			if err := t.applyItem(item); err != nil {
+				return err
+			}
+		}
+		tables[t.Name] = t
+		return nil
+	}
+
+	if m := createIndexRe.FindStringSubmatch(stmt); m != nil {
+		t, ok := tables[ident(m[3])]
+		if !ok {
+			return nil
+		}
+		name := ident(m[2])
+		if name == "" {
+			name = fmt.Sprintf("%s_idx%d", t.Name, len(t.Indexes))
+		}
+		t.Indexes = append(t.Indexes, introspect.Index{
+			Name:       name,
+			Columns:    identList(m[4]),
+			Unique:     m[1] != "",
+			Definition: stmt,
+		})
+		return nil
+	}
+
+	if m := alterTableRe.FindStringSubmatch(stmt); m != nil {
+		t, ok := tables[ident(m[1])]
+		if !ok {
+			return nil
+		}
+		for _, action := range splitTopLevel(m[2], ',') {
+			if err := t.applyAction(action); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if m := dropTableRe.FindStringSubmatch(stmt); m != nil {
+		for _, name := range identList(m[1]) {
+			delete(tables, name)
+		}
+	}
+	return nil
+}
+
+// applyItem applies a column definition or a table constraint of CREATE TABLE.
+func (t *Table) applyItem(item string) error {
+	if m := constraintRe.FindStringSubmatch(item); m != nil {
+		t.applyKey(ident(m[1]), m[2])
+		return nil
+	}
+	lower := strings.ToLower(item)
+	for _, prefix := range []string{"primary", "unique", "check", "foreign", "exclude", "like"} {
+		if strings.HasPrefix(lower, prefix) {
+			t.applyKey("", item)
+			return nil
+		}
+	}
+	return t.addColumn(item)
+}
+
+func (t *Table) applyAction(action string) error {
+	if m := alterColumnRe.FindStringSubmatch(action); m != nil {
+		for i := range t.Columns {
+			if t.Columns[i].Name == ident(m[1]) {
+				t.Columns[i].Nullable = strings.EqualFold(m[2], "drop")
+			}
+		}
+		return nil
+	}
+	if m := dropColumnRe.FindStringSubmatch(action); m != nil && !strings.EqualFold(ident(m[1]), "constraint") {
+		name := ident(m[1])
+		columns := t.Columns[:0]
+		for _, c := range t.Columns {
+			if c.Name != name {
+				columns = append(columns, c)
+			}
+		}
+		t.Columns = columns
+		return nil
+	}
+	if m := addColumnRe.FindStringSubmatch(action); m != nil {
+		return t.applyItem(m[1])
+	}
+	return nil
+}
+
+// applyKey adds an index for PRIMARY KEY and UNIQUE constraints, other constraints are ignored.
+func (t *Table) applyKey(name, def string) {
+	m := keyRe.FindStringSubmatch(def)
+	if m == nil {
+		return
+	}
+	primary := strings.HasPrefix(strings.ToLower(m[1]), "primary")
+	if name == "" {
+		suffix := "key"
+		if primary {
+			suffix = "pkey"
+		}
+		name = fmt.Sprintf("%s_%s", t.Name, suffix)
+	}
+
+	columns := identList(m[2])
+	t.Indexes = append(t.Indexes, introspect.Index{
+		Name:       name,
+		Columns:    columns,
+		Unique:     true,
+		Primary:    primary,
+		Definition: def,
+	})
+	if primary {
+		for i := range t.Columns {
+			for _, col := range columns {
+				if t.Columns[i].Name == col {
+					t.Columns[i].Nullable = false
+				}
+			}
+		}
+	}
+}
+
+func (t *Table) addColumn(def string) error {
+	tokens := strings.Fields(def)
+	if len(tokens) < 2 {
+		return errors.New("invalid column definition")
+	}
+
+	c := introspect.Column{
+		Table:    t.Name,
+		Name:     ident(tokens[0]),
+		Position: len(t.Columns) + 1,
+		Nullable: true,
+	}
+
+	i := 1
+	for i < len(tokens) && !columnKeywords[strings.ToLower(tokens[i])] {
+		i++
+	}
+	var serial bool
+	c.UDTName, c.DataType, serial = normalizeType(strings.Join(tokens[1:i], " "))
+	if serial {
+		c.Nullable = false
+		def := "nextval()"
+		c.Default = &def
+	}
+
+	rest := strings.ToLower(strings.Join(tokens[i:], " "))
+	switch {
+	case strings.Contains(rest, "generated always as identity"), strings.Contains(rest, "generated by default as identity"):
+		c.Identity = true
+		c.Nullable = false
+	case strings.Contains(rest, "generated always as"):
+		c.Generated = true
+	}
+	if strings.Contains(rest, "not null") {
+		c.Nullable = false
+	}
+	if strings.Contains(rest, "default ") {
+		def := strings.TrimSpace(strings.SplitN(rest, "default ", 2)[1])
+		c.Default = &def
+	}
+	t.Columns = append(t.Columns, c)
+
+	switch {
+	case strings.Contains(rest, "primary key"):
+		t.applyKey("", fmt.Sprintf("PRIMARY KEY (%s)", c.Name))
+	case strings.Contains(rest, "unique"):
+		t.applyKey(fmt.Sprintf("%s_%s_key", t.Name, c.Name), fmt.Sprintf("UNIQUE (%s)", c.Name))
+	}
+	return nil
+}
+
+// normalizeType returns udt name and information_schema data type of the SQL type.
+func normalizeType(typ string) (udt, dataType string, serial bool) {
+	udt, dataType = introspect.NormalizeType(typ)
+	return udt, dataType, dataType != "ARRAY" && strings.HasSuffix(strings.ToLower(strings.TrimSpace(typ)), "serial")
+}
+
+func stripComments(script string) string {
+	var b strings.Builder
+	for i := 0; i < len(script); i++ {
+		switch {
+		case strings.HasPrefix(script[i:], "--"):
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			b.WriteByte('\n')
+		case strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+			b.WriteByte(' ')
+		case script[i] == '\'':
+			end := strings.IndexByte(script[i+1:], '\'')
+			if end < 0 {
+				b.WriteString(script[i:])
+				return b.String()
+			}
+			b.WriteString(script[i : i+end+2])
+			i += end + 1
+		default:
+			b.WriteByte(script[i])
+		}
+	}
+	return b.String()
+}
+
+func splitStatements(script string) []string {
+	var result []string
+	for _, stmt := range splitTopLevel(script, ';') {
+		if stmt = strings.Join(strings.Fields(stmt), " "); stmt != "" {
+			result = append(result, stmt)
+		}
+	}
+	return result
+}
+
+// splitTopLevel splits s by sep outside of parentheses, quotes and dollar-quoted strings.
+func splitTopLevel(s string, sep byte) []string {
+	var (
+		parts  []string
+		depth  int
+		quote  byte
+		dollar string
+		start  int
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case dollar != "":
+			if strings.HasPrefix(s[i:], dollar) {
+				i += len(dollar) - 1
+				dollar = ""
+			}
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '$':
+			if end := strings.IndexByte(s[i+1:], '$'); end >= 0 && isIdent(s[i+1:i+1+end]) {
+				dollar = s[i : i+end+2]
+				i += end + 1
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" {
+		parts = append(parts, rest)
+	}
+	return parts
+}
+
+func isIdent(s string) bool {
+	for _, c := range s {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// ident returns an unquoted identifier without schema, lower-cased unless quoted.
+func ident(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+	if strings.HasPrefix(s, `"`) {
+		return strings.Trim(s, `"`)
+	}
+	return strings.ToLower(s)
+}
+
+// identList parses a comma separated list of column names, ignoring sort options and expressions.
+func identList(s string) []string {
+	var result []string
+	for _, part := range splitTopLevel(s, ',') {
+		fields := strings.Fields(part)
+		if len(fields) == 0 || strings.Contains(fields[0], "(") {
+			continue
+		}
+		result = append(result, ident(fields[0]))
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package gen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// describe lists columns as "name udt" with " null" for nullable ones and indexes as "name(columns)"
+// with "unique" or "primary" markers.
+func describe(t Table) []string {
+	var result []string
+	for _, c := range t.Columns {
+		s := c.Name + " " + c.UDTName
+		if c.Nullable {
+			s += " null"
+		}
+		result = append(result, s)
+	}
+	for _, i := range t.Indexes {
+		s := i.Name + "(" + strings.Join(i.Columns, ",") + ")"
+		switch {
+		case i.Primary:
+			s += " primary"
+		case i.Unique:
+			s += " unique"
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func TestFromSQL(t *testing.T) {
+	cases := []struct {
+		name     string
+		scripts  []string
+		expected map[string][]string
+	}{
+		{
+			name: "create table",
+			scripts: []string{`
+-- users of the service; comments may contain ; and (
+CREATE TABLE IF NOT EXISTS public."Users" (
+    id    BIGSERIAL,
+    email TEXT NOT NULL UNIQUE, /* inline; comment */
+    note  VARCHAR(255) DEFAULT 'a;b',
+    CONSTRAINT users_pk PRIMARY KEY (id)
+);`},
+			expected: map[string][]string{"Users": {
+				"id int8", "email text", "note varchar null",
+				"Users_email_key(email) unique", "users_pk(id) primary",
+			}},
+		},
+		{
+			name: "alter and index",
+			scripts: []string{
+				`CREATE TABLE entries (id BIGINT PRIMARY KEY, name TEXT);`,
+				`ALTER TABLE entries ADD COLUMN score INTEGER NOT NULL DEFAULT 0, ALTER COLUMN name SET NOT NULL;
+CREATE UNIQUE INDEX CONCURRENTLY entries_name_idx ON entries USING btree (lower(name), name DESC);
+CREATE INDEX ON entries (score);
+ALTER TABLE entries DROP COLUMN IF EXISTS score;`,
+			},
+			expected: map[string][]string{"entries": {
+				"id int8", "name text",
+				"entries_pkey(id) primary", "entries_name_idx(name) unique", "entries_idx2(score)",
+			}},
+		},
+		{
+			name: "functions and dropped tables",
+			scripts: []string{`
+CREATE TABLE a (id INT);
+CREATE TABLE b (id INT);
+CREATE FUNCTION f() RETURNS trigger AS $body$
+BEGIN
+    CREATE TABLE c (id INT);
+    RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql;
+DROP TABLE IF EXISTS a, missing CASCADE;`},
+			expected: map[string][]string{"b": {"id int4 null"}},
+		},
+	}
+
+	for _, c := range cases {
+		tables, err := FromSQL(c.scripts...)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		got := make(map[string][]string, len(tables))
+		for _, table := range tables {
+			got[table.Name] = describe(table)
+		}
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, got)
+		}
+	}
+
+	if _, err := FromSQL("CREATE TABLE t (id)"); err == nil {
+		t.Fatalf("expected error for a column without type")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		script   string
+		expected []string
+	}{
+		{script: "SELECT 1; SELECT 2;", expected: []string{"SELECT 1", "SELECT 2"}},
+		{script: "SELECT ';';\n\n;", expected: []string{"SELECT ';'"}},
+		{script: `SELECT "a;b" FROM t`, expected: []string{`SELECT "a;b" FROM t`}},
+		{script: "DO $$ BEGIN PERFORM 1; END $$; SELECT 1", expected: []string{"DO $$ BEGIN PERFORM 1; END $$", "SELECT 1"}},
+		{script: "SELECT $1; SELECT $2", expected: []string{"SELECT $1", "SELECT $2"}},
+		{script: "  ", expected: nil},
+	}
+
+	for _, c := range cases {
+		if got := splitStatements(c.script); !reflect.DeepEqual(got, c.expected) {
+			t.Fatalf("%q: expected %q, got %q", c.script, c.expected, got)
+		}
+	}
+}
+
+func TestIdent(t *testing.T) {
+	cases := map[string]string{
+		"Users":            "users",
+		`"Users"`:          "Users",
+		"public.entries":   "entries",
+		` "app"."Entries"`: "Entries",
+	}
+	for s, expected := range cases {
+		if got := ident(s); got != expected {
+			t.Fatalf("%q: expected %q, got %q", s, expected, got)
+		}
+	}
+}
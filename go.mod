@@ -4,7 +4,9 @@ go 1.16
 
 require (
 	github.com/Masterminds/squirrel v1.4.0
-	github.com/fatih/structs v1.1.0
+	github.com/jmoiron/sqlx v1.2.0
+	github.com/lib/pq v1.8.0
 	gitlab.com/distributed_lab/kit v1.8.6
 	gitlab.com/distributed_lab/logan v3.8.0+incompatible
+	gopkg.in/yaml.v2 v2.2.8
 )
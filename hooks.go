@@ -0,0 +1,132 @@
+package pg_dao
+
+import (
+	"context"
+	"reflect"
+)
+
+// BeforeCreator is implemented by DTOs that have to be validated or defaulted before insert.
+// Pass a pointer to Create to let the hook modify the DTO.
+type BeforeCreator interface {
+	BeforeCreate(ctx context.Context) error
+}
+
+// AfterCreator is implemented by DTOs that have to be notified after insert.
+type AfterCreator interface {
+	AfterCreate(ctx context.Context) error
+}
+
+// AfterFinder is implemented by DTOs that have to be post-processed after Get or Select.
+type AfterFinder interface {
+	AfterFind(ctx context.Context) error
+}
+
+// Hooks describes DAO-level callbacks. Any of them can be nil.
+// An error returned by a hook is passed to the caller as is, aborting the operation
+// and rolling back the surrounding transaction.
+type Hooks struct {
+	BeforeCreate func(ctx context.Context, dto interface{}) error
+	AfterCreate  func(ctx context.Context, id int64, dto interface{}) error
+	BeforeUpdate func(ctx context.Context, q DAO) error
+	AfterUpdate  func(ctx context.Context, q DAO) error
+	BeforeDelete func(ctx context.Context, q DAO) error
+	AfterDelete  func(ctx context.Context, q DAO) error
+	AfterFind    func(ctx context.Context, dto interface{}) error
+}
+
+// WithHooks registers DAO-level hooks. Hooks registered by several options run in registration order.
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hooks)
+	}
+}
+
+// beforeCreate runs BeforeCreate hooks. Hooks are skipped in dry-run mode since they may have side effects.
+func (d *dao) beforeCreate(ctx context.Context, dto interface{}) error {
+	if d.dryRun {
+		return nil
+	}
+	if h, ok := dto.(BeforeCreator); ok {
+		if err := h.BeforeCreate(ctx); err != nil {
+			return err
+		}
+	}
+	for _, h := range d.opts.hooks {
+		if h.BeforeCreate == nil {
+			continue
+		}
+		if err := h.BeforeCreate(ctx, dto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dao) afterCreate(ctx context.Context, id int64, dto interface{}) error {
+	if h, ok := dto.(AfterCreator); ok {
+		if err := h.AfterCreate(ctx); err != nil {
+			return err
+		}
+	}
+	for _, h := range d.opts.hooks {
+		if h.AfterCreate == nil {
+			continue
+		}
+		if err := h.AfterCreate(ctx, id, dto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runQueryHooks runs DAO-level update or delete hooks, skipped in dry-run mode like BeforeCreate hooks.
+func (d *dao) runQueryHooks(ctx context.Context, hook func(Hooks) func(context.Context, DAO) error) error {
+	if d.dryRun {
+		return nil
+	}
+	for _, h := range d.opts.hooks {
+		fn := hook(h)
+		if fn == nil {
+			continue
+		}
+		if err := fn(ctx, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dao) afterFind(ctx context.Context, dto interface{}) error {
+	if h, ok := dto.(AfterFinder); ok {
+		if err := h.AfterFind(ctx); err != nil {
+			return err
+		}
+	}
+	for _, h := range d.opts.hooks {
+		if h.AfterFind == nil {
+			continue
+		}
+		if err := h.AfterFind(ctx, dto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterFindAll runs AfterFind hooks for every element of the slice list points to.
+func (d *dao) afterFindAll(ctx context.Context, list interface{}) error {
+	slice := reflect.ValueOf(list).Elem()
+	if slice.Kind() != reflect.Slice {
+		return nil
+	}
+	for i := 0; i < slice.Len(); i++ {
+		elem := slice.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		if err := d.afterFind(ctx, elem.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Package introspect reads the structure of PostgreSQL tables from information_schema and pg_catalog.
+// All queries are limited to the current schema of the connection unless WithSchema is used.
+package introspect
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lib/pq"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// Constraint types as stored in pg_constraint.contype.
+const (
+	ConstraintPrimaryKey = "p"
+	ConstraintUnique     = "u"
+	ConstraintForeignKey = "f"
+	ConstraintCheck      = "c"
+	ConstraintExclusion  = "x"
+)
+
+// Column describes a table column. DataType is information_schema data type (e.g. "character varying"
+// or "USER-DEFINED" for enums) and UDTName is the underlying type name (e.g. "varchar", "_text", "mood").
+type Column struct {
+	Table     string  `db:"table_name"`
+	Name      string  `db:"column_name"`
+	Position  int     `db:"ordinal_position"`
+	DataType  string  `db:"data_type"`
+	UDTName   string  `db:"udt_name"`
+	Nullable  bool    `db:"nullable"`
+	Default   *string `db:"column_default"`
+	Identity  bool    `db:"identity"`
+	Generated bool    `db:"generated"`
+}
+
+// Required reports whether a value has to be provided for the column on insert.
+func (c Column) Required() bool {
+	return !c.Nullable && c.Default == nil && !c.Identity && !c.Generated
+}
+
+// IsArray reports whether the column has an array type.
+func (c Column) IsArray() bool {
+	return c.DataType == "ARRAY"
+}
+
+// SQLType returns the column type as written in DDL, without type modifiers.
+func (c Column) SQLType() string {
+	switch c.DataType {
+	case "ARRAY":
+		return strings.TrimPrefix(c.UDTName, "_") + "[]"
+	case "USER-DEFINED", "":
+		return c.UDTName
+	}
+	return c.DataType
+}
+
+// Index describes a table index. Expression parts of an index are not listed in Columns.
+type Index struct {
+	Name       string         `db:"name"`
+	Columns    pq.StringArray `db:"columns"`
+	Unique     bool           `db:"is_unique"`
+	Primary    bool           `db:"is_primary"`
+	Definition string         `db:"definition"`
+}
+
+// Constraint describes a table constraint. ReferencedTable is set for foreign keys only.
+type Constraint struct {
+	Name            string         `db:"name"`
+	Type            string         `db:"type"`
+	Columns         pq.StringArray `db:"columns"`
+	ReferencedTable string         `db:"referenced_table"`
+	Definition      string         `db:"definition"`
+}
+
+// Inspector reads the structure of tables of the current schema.
+type Inspector struct {
+	db *pgdb.DB
+	// schema is empty for the current schema.
+	schema string
+}
+
+// An Option configures Inspector.
+type Option func(*Inspector)
+
+// WithSchema makes Inspector read tables of the schema instead of the current one.
+func WithSchema(schema string) Option {
+	return func(i *Inspector) {
+		i.schema = schema
+	}
+}
+
+func New(db *pgdb.DB, opts ...Option) *Inspector {
+	i := &Inspector{db: db}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Tables returns names of all base tables.
+func (i *Inspector) Tables(ctx context.Context) ([]string, error) {
+	var tables []string
+	err := i.db.SelectRawContext(ctx, &tables, `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = COALESCE(NULLIF(?, ''), current_schema()) AND table_type = 'BASE TABLE'
+		ORDER BY table_name`, i.schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select tables")
+	}
+	return tables, nil
+}
+
+// Columns returns columns of the table ordered by position. It returns no columns if the table does not exist.
+func (i *Inspector) Columns(ctx context.Context, table string) ([]Column, error) {
+	var columns []Column
+	err := i.db.SelectRawContext(ctx, &columns, `
+		SELECT table_name, column_name, ordinal_position, data_type, udt_name,
+			is_nullable = 'YES' AS nullable, column_default,
+			is_identity = 'YES' AS identity, is_generated = 'ALWAYS' AS generated
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+		ORDER BY ordinal_position`, i.schema, table)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select columns", map[string]interface{}{"table": table})
+	}
+	return columns, nil
+}
+
+// Indexes returns indexes of the table ordered by name.
+func (i *Inspector) Indexes(ctx context.Context, table string) ([]Index, error) {
+	var indexes []Index
+	err := i.db.SelectRawContext(ctx, &indexes, `
+		SELECT i.relname AS name,
+			ARRAY(
+				SELECT a.attname
+				FROM unnest(ix.indkey) WITH ORDINALITY AS k(attnum, n)
+				JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = k.attnum
+				ORDER BY k.n
+			) AS columns,
+			ix.indisunique AS is_unique,
+			ix.indisprimary AS is_primary,
+			pg_get_indexdef(ix.indexrelid) AS definition
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace ns ON ns.oid = t.relnamespace
+		WHERE ns.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND t.relname = ?
+		ORDER BY i.relname`, i.schema, table)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select indexes", map[string]interface{}{"table": table})
+	}
+	return indexes, nil
+}
+
+// Constraints returns constraints of the table ordered by name.
+func (i *Inspector) Constraints(ctx context.Context, table string) ([]Constraint, error) {
+	var constraints []Constraint
+	err := i.db.SelectRawContext(ctx, &constraints, `
+		SELECT c.conname AS name,
+			c.contype::text AS type,
+			ARRAY(
+				SELECT a.attname
+				FROM unnest(c.conkey) WITH ORDINALITY AS k(attnum, n)
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+				ORDER BY k.n
+			) AS columns,
+			COALESCE(f.relname, '') AS referenced_table,
+			pg_get_constraintdef(c.oid) AS definition
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace ns ON ns.oid = t.relnamespace
+		LEFT JOIN pg_class f ON f.oid = c.confrelid
+		WHERE ns.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND t.relname = ?
+		ORDER BY c.conname`, i.schema, table)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select constraints", map[string]interface{}{"table": table})
+	}
+	return constraints, nil
+}
@@ -0,0 +1,134 @@
+package introspect
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+	bytesType   = reflect.TypeOf([]byte(nil))
+)
+
+var timeTypes = map[string]bool{
+	"timestamp":   true,
+	"timestamptz": true,
+	"date":        true,
+	"time":        true,
+	"timetz":      true,
+}
+
+var intTypes = map[string]bool{
+	"int2": true,
+	"int4": true,
+	"int8": true,
+	"oid":  true,
+}
+
+// Compatible reports whether values of t can be both written to and read from the column
+// by lib/pq and sqlx. Types implementing sql.Scanner or driver.Valuer are assumed to be compatible.
+func Compatible(t reflect.Type, c Column) bool {
+	if t.Kind() == reflect.Ptr && !t.Implements(scannerType) {
+		t = t.Elem()
+	}
+	if t.Implements(valuerType) || reflect.PtrTo(t).Implements(scannerType) {
+		return true
+	}
+	if c.IsArray() {
+		return false
+	}
+
+	switch {
+	case t == timeType:
+		return timeTypes[c.UDTName]
+	case t == bytesType:
+		return !timeTypes[c.UDTName]
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return c.UDTName == "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return intTypes[c.UDTName]
+	case reflect.Float32, reflect.Float64:
+		return intTypes[c.UDTName] || c.UDTName == "float4" || c.UDTName == "float8" || c.UDTName == "numeric"
+	case reflect.String:
+		return !timeTypes[c.UDTName]
+	}
+	return false
+}
+
+// Nullable reports whether values of t can hold NULL read from a column.
+func Nullable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return reflect.PtrTo(t).Implements(scannerType)
+}
+
+var typeAliases = map[string]string{
+	"smallint":                    "int2",
+	"integer":                     "int4",
+	"int":                         "int4",
+	"bigint":                      "int8",
+	"smallserial":                 "int2",
+	"serial":                      "int4",
+	"bigserial":                   "int8",
+	"real":                        "float4",
+	"double precision":            "float8",
+	"decimal":                     "numeric",
+	"boolean":                     "bool",
+	"character varying":           "varchar",
+	"character":                   "bpchar",
+	"char":                        "bpchar",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+	"time without time zone":      "time",
+	"time with time zone":         "timetz",
+}
+
+var dataTypes = map[string]string{
+	"int2": "smallint", "int4": "integer", "int8": "bigint", "float4": "real", "float8": "double precision",
+	"numeric": "numeric", "bool": "boolean", "varchar": "character varying", "bpchar": "character", "text": "text",
+	"timestamp": "timestamp without time zone", "timestamptz": "timestamp with time zone", "date": "date",
+	"time": "time without time zone", "timetz": "time with time zone", "json": "json", "jsonb": "jsonb",
+	"uuid": "uuid", "bytea": "bytea", "inet": "inet", "cidr": "cidr", "interval": "interval",
+}
+
+// NormalizeType returns udt name and information_schema data type of the SQL type as written in DDL,
+// e.g. "int8" and "bigint" for "bigserial" or "_varchar" and "ARRAY" for "varchar(64)[]".
+// Type modifiers are ignored.
+func NormalizeType(typ string) (udt, dataType string) {
+	typ = strings.ToLower(strings.Join(strings.Fields(typ), " "))
+	array := strings.HasSuffix(typ, "[]")
+	typ = strings.TrimSuffix(typ, "[]")
+	if i := strings.Index(typ, "("); i >= 0 {
+		if j := strings.Index(typ, ")"); j > i {
+			typ = strings.TrimSpace(typ[:i] + typ[j+1:])
+		}
+	}
+
+	udt = typ
+	if alias, ok := typeAliases[typ]; ok {
+		udt = alias
+	}
+	if i := strings.LastIndex(udt, "."); i >= 0 {
+		udt = udt[i+1:]
+	}
+
+	if array {
+		return "_" + udt, "ARRAY"
+	}
+	dataType, ok := dataTypes[udt]
+	if !ok {
+		dataType = "USER-DEFINED"
+	}
+	return udt, dataType
+}
@@ -0,0 +1,146 @@
+package pg_dao
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// JSON filters are passed to Filter and compose with other filters of the session. Values are marshaled
+// and passed as parameters. Key operators are built with jsonb_exists functions since pgdb treats every
+// question mark as a placeholder.
+
+// JSONOperator is the operator of JSONCondition.
+type JSONOperator string
+
+const (
+	JSONOpContains   JSONOperator = "@>"
+	JSONOpHasKey     JSONOperator = "?"
+	JSONOpHasAnyKey  JSONOperator = "?|"
+	JSONOpHasAllKeys JSONOperator = "?&"
+	JSONOpPathExists JSONOperator = "@?"
+	JSONOpPathEq     JSONOperator = "="
+	JSONOpPathNotEq  JSONOperator = "<>"
+	JSONOpPathIsNull JSONOperator = "IS NULL"
+)
+
+// JSONCondition matches rows by the jsonb Column. It is built by JSONContains, the key filters and
+// methods of JSONPathExpr.
+type JSONCondition struct {
+	Column string
+	Op     JSONOperator
+	// Keys are the top-level keys of the key operators.
+	Keys []string
+	// Path is the path of the value compared by JSONOpPathEq, JSONOpPathNotEq and JSONOpPathIsNull.
+	Path []string
+	// Value is the contained value, the compared value or the SQL/JSON path of JSONOpPathExists.
+	Value interface{}
+}
+
+func (c JSONCondition) ToSql() (string, []interface{}, error) {
+	path := pq.StringArray(c.Path)
+	if path == nil {
+		path = pq.StringArray{}
+	}
+
+	switch c.Op {
+	case JSONOpContains:
+		return c.Column + " @> ?::jsonb", []interface{}{JSON{V: c.Value}}, nil
+	case JSONOpHasKey:
+		if len(c.Keys) != 1 {
+			return "", nil, errors.New("json key condition requires one key")
+		}
+		return "jsonb_exists(" + c.Column + ", ?)", []interface{}{c.Keys[0]}, nil
+	case JSONOpHasAnyKey:
+		return "jsonb_exists_any(" + c.Column + ", ?)", []interface{}{pq.StringArray(c.Keys)}, nil
+	case JSONOpHasAllKeys:
+		return "jsonb_exists_all(" + c.Column + ", ?)", []interface{}{pq.StringArray(c.Keys)}, nil
+	case JSONOpPathExists:
+		return "jsonb_path_exists(" + c.Column + ", ?::jsonpath)", []interface{}{c.Value}, nil
+	case JSONOpPathEq, JSONOpPathNotEq:
+		if s, ok := c.Value.(string); ok {
+			return "(" + c.Column + " #>> ?) " + string(c.Op) + " ?", []interface{}{path, s}, nil
+		}
+		return "(" + c.Column + " #> ?) " + string(c.Op) + " ?::jsonb", []interface{}{path, JSON{V: c.Value}}, nil
+	case JSONOpPathIsNull:
+		return "(" + c.Column + " #>> ?) IS NULL", []interface{}{path}, nil
+	}
+	return "", nil, errors.From(errors.New("unexpected json operator"), map[string]interface{}{"op": c.Op})
+}
+
+// JSONContains matches rows whose jsonb column contains value: col @> value.
+func JSONContains(col string, value interface{}) JSONCondition {
+	return JSONCondition{Column: col, Op: JSONOpContains, Value: value}
+}
+
+// JSONHasKey matches rows whose jsonb column has the top-level key: col ? key.
+func JSONHasKey(col, key string) JSONCondition {
+	return JSONCondition{Column: col, Op: JSONOpHasKey, Keys: []string{key}}
+}
+
+// JSONHasAnyKey matches rows whose jsonb column has any of the top-level keys: col ?| keys.
+func JSONHasAnyKey(col string, keys ...string) JSONCondition {
+	return JSONCondition{Column: col, Op: JSONOpHasAnyKey, Keys: keys}
+}
+
+// JSONHasAllKeys matches rows whose jsonb column has all of the top-level keys: col ?& keys.
+func JSONHasAllKeys(col string, keys ...string) JSONCondition {
+	return JSONCondition{Column: col, Op: JSONOpHasAllKeys, Keys: keys}
+}
+
+// JSONPathExists matches rows whose jsonb column has an item at the SQL/JSON path,
+// e.g. "$.tags[*] ? (@ == \"go\")". Requires PostgreSQL 12.
+func JSONPathExists(col, path string) JSONCondition {
+	return JSONCondition{Column: col, Op: JSONOpPathExists, Value: path}
+}
+
+// JSONPathExpr is a value nested in a jsonb column. As a Sqlizer it is the text of the value: col #>> path.
+type JSONPathExpr struct {
+	col  string
+	path pq.StringArray
+}
+
+// JSONPath returns the value of jsonb column at the path written as "$.a.b[0]" or "a.b.0".
+func JSONPath(col, path string) JSONPathExpr {
+	return JSONPathExpr{col: col, path: parseJSONPath(path)}
+}
+
+func (e JSONPathExpr) ToSql() (string, []interface{}, error) {
+	return "(" + e.col + " #>> ?)", []interface{}{e.path}, nil
+}
+
+// Eq matches rows whose value at the path equals val. Strings are compared with the text of the value,
+// other values with the jsonb value, so numbers, booleans and objects are compared by their JSON types.
+func (e JSONPathExpr) Eq(val interface{}) JSONCondition {
+	return JSONCondition{Column: e.col, Op: JSONOpPathEq, Path: e.path, Value: val}
+}
+
+// NotEq matches rows whose value at the path differs from val, see Eq.
+func (e JSONPathExpr) NotEq(val interface{}) JSONCondition {
+	return JSONCondition{Column: e.col, Op: JSONOpPathNotEq, Path: e.path, Value: val}
+}
+
+// IsNull matches rows that have no value at the path or have JSON null there.
+func (e JSONPathExpr) IsNull() JSONCondition {
+	return JSONCondition{Column: e.col, Op: JSONOpPathIsNull, Path: e.path}
+}
+
+var jsonPathIndexRe = regexp.MustCompile(`\[(\d+)\]`)
+
+func parseJSONPath(path string) pq.StringArray {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = jsonPathIndexRe.ReplaceAllString(path, ".$1")
+
+	var result pq.StringArray
+	for _, part := range strings.Split(path, ".") {
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+	if result == nil {
+		result = pq.StringArray{}
+	}
+	return result
+}
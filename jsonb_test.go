@@ -0,0 +1,48 @@
+package pg_dao_test
+
+import (
+	"reflect"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	pg "github.com/olegfomenko/pg-dao"
+)
+
+func TestJSONConditionSQL(t *testing.T) {
+	meta := map[string]string{"k": "v"}
+	cases := []struct {
+		name string
+		cond sq.Sqlizer
+		sql  string
+		args []interface{}
+	}{
+		{name: "contains", cond: pg.JSONContains("meta", meta), sql: "meta @> ?::jsonb", args: []interface{}{pg.JSON{V: meta}}},
+		{name: "has key", cond: pg.JSONHasKey("meta", "k"), sql: "jsonb_exists(meta, ?)", args: []interface{}{"k"}},
+		{name: "has any key", cond: pg.JSONHasAnyKey("meta", "a", "b"), sql: "jsonb_exists_any(meta, ?)",
+			args: []interface{}{pq.StringArray{"a", "b"}}},
+		{name: "has all keys", cond: pg.JSONHasAllKeys("meta", "a"), sql: "jsonb_exists_all(meta, ?)",
+			args: []interface{}{pq.StringArray{"a"}}},
+		{name: "path exists", cond: pg.JSONPathExists("meta", "$.a"), sql: "jsonb_path_exists(meta, ?::jsonpath)",
+			args: []interface{}{"$.a"}},
+		{name: "path text", cond: pg.JSONPath("meta", "$.a.b[0]").Eq("x"), sql: "(meta #>> ?) = ?",
+			args: []interface{}{pq.StringArray{"a", "b", "0"}, "x"}},
+		{name: "path jsonb", cond: pg.JSONPath("meta", "a").NotEq(1), sql: "(meta #> ?) <> ?::jsonb",
+			args: []interface{}{pq.StringArray{"a"}, pg.JSON{V: 1}}},
+		{name: "path null", cond: pg.JSONPath("meta", "$").IsNull(), sql: "(meta #>> ?) IS NULL",
+			args: []interface{}{pq.StringArray{}}},
+	}
+	for _, c := range cases {
+		sql, args, err := c.cond.ToSql()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if sql != c.sql || !reflect.DeepEqual(args, c.args) {
+			t.Fatalf("%s: expected %s %v, got %s %v", c.name, c.sql, c.args, sql, args)
+		}
+	}
+
+	if _, _, err := (pg.JSONCondition{Column: "meta", Op: "~"}).ToSql(); err == nil {
+		t.Fatalf("expected error for unknown operator")
+	}
+}
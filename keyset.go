@@ -0,0 +1,271 @@
+package pg_dao
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// KeysetColumn is a column of the order pages are selected in.
+type KeysetColumn struct {
+	Name string
+	Desc bool
+}
+
+// KeysetParams are params of keyset pagination. Columns have to identify rows uniquely and have no NULL values,
+// e.g. created_at and id.
+type KeysetParams struct {
+	Columns []KeysetColumn
+	// Limit is the page size, 15 by default.
+	Limit uint64
+	// Cursor is KeysetPage.Next or KeysetPage.Prev of the previous page, empty for the first page.
+	Cursor string
+}
+
+// KeysetPage holds cursors of pages adjacent to the selected one.
+type KeysetPage struct {
+	// Next is the cursor of the following page, empty if the selected page is the last one.
+	Next string
+	// Prev is the cursor of the preceding page, empty if the selected page is the first one.
+	Prev string
+}
+
+// KeysetCondition matches rows following the key Values in the order of Columns. Columns of the same
+// direction are compared as a row, e.g. (created_at, id) < (?, ?), mixed directions are expanded to
+// (a > ?) OR (a = ? AND b < ?).
+type KeysetCondition struct {
+	Columns []KeysetColumn
+	Values  []interface{}
+}
+
+func (c KeysetCondition) ToSql() (string, []interface{}, error) {
+	if len(c.Columns) == 0 || len(c.Columns) != len(c.Values) {
+		return "", nil, errors.New("keyset condition requires a value for every column")
+	}
+
+	uniform := true
+	for _, col := range c.Columns {
+		uniform = uniform && col.Desc == c.Columns[0].Desc
+	}
+	if uniform {
+		names := make([]string, len(c.Columns))
+		placeholders := make([]string, len(c.Columns))
+		for i, col := range c.Columns {
+			names[i] = col.Name
+			placeholders[i] = "?"
+		}
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(names, ", "), keysetOperator(c.Columns[0]),
+			strings.Join(placeholders, ", ")), c.Values, nil
+	}
+
+	var (
+		parts []string
+		args  []interface{}
+	)
+	for i, col := range c.Columns {
+		conds := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, c.Columns[j].Name+" = ?")
+			args = append(args, c.Values[j])
+		}
+		conds = append(conds, col.Name+" "+keysetOperator(col)+" ?")
+		args = append(args, c.Values[i])
+		parts = append(parts, "("+strings.Join(conds, " AND ")+")")
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", args, nil
+}
+
+func keysetOperator(col KeysetColumn) string {
+	if col.Desc {
+		return "<"
+	}
+	return ">"
+}
+
+// cursor is the content of opaque cursor tokens.
+type cursor struct {
+	Key []json.RawMessage `json:"k"`
+	// Backward cursors select rows preceding the key.
+	Backward bool `json:"b,omitempty"`
+}
+
+// SelectPage selects a page with keyset pagination, see SelectPageCtx.
+func SelectPage(q DAO, list interface{}, params KeysetParams) (KeysetPage, error) {
+	return SelectPageCtx(context.TODO(), q, list, params)
+}
+
+// SelectPageCtx selects a page of rows of q into list, which is a pointer to a slice of DTOs,
+// in the order of params.Columns. Any DAO with filters applied can be paged, e.g. the in-memory one.
+func SelectPageCtx(ctx context.Context, q DAO, list interface{}, params KeysetParams) (KeysetPage, error) {
+	if len(params.Columns) == 0 {
+		return KeysetPage{}, errors.New("keyset columns are required")
+	}
+	if params.Limit == 0 {
+		params.Limit = 15
+	}
+
+	dest := reflect.ValueOf(list)
+	if dest.Kind() != reflect.Ptr || dest.Elem().Kind() != reflect.Slice {
+		return KeysetPage{}, errors.New("argument is not a slice pointer")
+	}
+	fields, err := keysetFields(dest.Elem().Type().Elem(), params.Columns)
+	if err != nil {
+		return KeysetPage{}, err
+	}
+
+	var c cursor
+	if params.Cursor != "" {
+		if c, err = decodeCursor(params.Cursor, len(fields)); err != nil {
+			return KeysetPage{}, err
+		}
+	}
+
+	columns := params.Columns
+	if c.Backward {
+		columns = make([]KeysetColumn, len(params.Columns))
+		for i, col := range params.Columns {
+			columns[i] = KeysetColumn{Name: col.Name, Desc: !col.Desc}
+		}
+	}
+	if c.Key != nil {
+		values := make([]interface{}, len(fields))
+		for i, f := range fields {
+			val := reflect.New(f.typ)
+			if err := json.Unmarshal(c.Key[i], val.Interface()); err != nil {
+				return KeysetPage{}, errors.Wrap(ErrInvalidCursor, "failed to decode key", map[string]interface{}{
+					"column": f.column,
+					"error":  err.Error(),
+				})
+			}
+			values[i] = val.Elem().Interface()
+		}
+		q = q.Filter(KeysetCondition{Columns: columns, Values: values})
+	}
+	for _, col := range columns {
+		if col.Desc {
+			q = q.OrderByDesc(col.Name)
+		} else {
+			q = q.OrderByAsc(col.Name)
+		}
+	}
+
+	// One more row is selected to find out whether there is a page after the selected one.
+	if err := q.Limit(params.Limit+1).SelectCtx(ctx, list); err != nil {
+		return KeysetPage{}, err
+	}
+
+	rows := dest.Elem()
+	more := uint64(rows.Len()) > params.Limit
+	if more {
+		rows.Set(rows.Slice(0, int(params.Limit)))
+	}
+	if c.Backward {
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+	if rows.Len() == 0 {
+		return KeysetPage{}, nil
+	}
+
+	// Backward pages are selected from a page following them, forward pages from a preceding one.
+	var page KeysetPage
+	if more || c.Backward {
+		if page.Next, err = encodeCursor(rows.Index(rows.Len()-1), fields, false); err != nil {
+			return KeysetPage{}, err
+		}
+	}
+	if more && c.Backward || !c.Backward && c.Key != nil {
+		if page.Prev, err = encodeCursor(rows.Index(0), fields, true); err != nil {
+			return KeysetPage{}, err
+		}
+	}
+	return page, nil
+}
+
+// keysetFields returns fields of elem, a DTO or a pointer to DTO type, mapped to columns.
+func keysetFields(elem reflect.Type, columns []KeysetColumn) ([]field, error) {
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, errors.From(errors.New("keyset pagination requires a slice of structs"), map[string]interface{}{
+			"type": elem,
+		})
+	}
+
+	m := mappingOf(elem, false)
+	result := make([]field, len(columns))
+	for i, col := range columns {
+		name := col.Name[strings.LastIndex(col.Name, ".")+1:]
+		found := false
+		for _, f := range m.fields {
+			if f.column == name {
+				result[i], found = f, true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.From(errors.New("keyset column is not mapped to a field"), map[string]interface{}{
+				"column": col.Name,
+				"type":   elem,
+			})
+		}
+	}
+	return result, nil
+}
+
+func encodeCursor(row reflect.Value, fields []field, backward bool) (string, error) {
+	row = reflect.Indirect(row)
+	c := cursor{Key: make([]json.RawMessage, len(fields)), Backward: backward}
+	for i, f := range fields {
+		fv, ok := fieldByIndex(row, f.index, false)
+		if !ok {
+			return "", errors.From(errors.New("keyset column value is missing"), map[string]interface{}{
+				"column": f.column,
+			})
+		}
+		data, err := json.Marshal(fv.Interface())
+		if err != nil {
+			return "", errors.Wrap(err, "failed to encode key", map[string]interface{}{"column": f.column})
+		}
+		c.Key[i] = data
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(token string, columns int) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, errors.Wrap(ErrInvalidCursor, "malformed token")
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, errors.Wrap(ErrInvalidCursor, "malformed token")
+	}
+	if len(c.Key) != columns {
+		return c, errors.Wrap(ErrInvalidCursor, "cursor has been issued for other columns")
+	}
+	return c, nil
+}
+
+// checkOrder reports orders pgdb page params panic on.
+func checkOrder(order string) error {
+	switch order {
+	case "", pgdb.OrderTypeAsc, pgdb.OrderTypeDesc:
+		return nil
+	}
+	return errors.From(errors.New("unexpected order type"), map[string]interface{}{"order": order})
+}
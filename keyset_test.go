@@ -0,0 +1,145 @@
+package pg_dao_test
+
+import (
+	"reflect"
+	"testing"
+
+	pg "github.com/olegfomenko/pg-dao"
+	"github.com/olegfomenko/pg-dao/pgdaotest"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+func TestKeysetConditionSQL(t *testing.T) {
+	cases := []struct {
+		name  string
+		cond  pg.KeysetCondition
+		sql   string
+		args  []interface{}
+		fails bool
+	}{
+		{
+			name: "ascending",
+			cond: pg.KeysetCondition{
+				Columns: []pg.KeysetColumn{{Name: "created_at"}, {Name: "id"}},
+				Values:  []interface{}{epoch, 5},
+			},
+			sql:  "(created_at, id) > (?, ?)",
+			args: []interface{}{epoch, 5},
+		},
+		{
+			name: "descending",
+			cond: pg.KeysetCondition{
+				Columns: []pg.KeysetColumn{{Name: "id", Desc: true}},
+				Values:  []interface{}{5},
+			},
+			sql:  "(id) < (?)",
+			args: []interface{}{5},
+		},
+		{
+			name: "mixed",
+			cond: pg.KeysetCondition{
+				Columns: []pg.KeysetColumn{{Name: "score", Desc: true}, {Name: "name"}, {Name: "id"}},
+				Values:  []interface{}{3, "b", 7},
+			},
+			sql:  "((score < ?) OR (score = ? AND name > ?) OR (score = ? AND name = ? AND id > ?))",
+			args: []interface{}{3, 3, "b", 3, "b", 7},
+		},
+		{
+			name:  "missing value",
+			cond:  pg.KeysetCondition{Columns: []pg.KeysetColumn{{Name: "id"}, {Name: "name"}}, Values: []interface{}{1}},
+			fails: true,
+		},
+		{
+			name:  "no columns",
+			cond:  pg.KeysetCondition{},
+			fails: true,
+		},
+	}
+
+	for _, c := range cases {
+		sql, args, err := c.cond.ToSql()
+		if c.fails {
+			if err == nil {
+				t.Fatalf("%s: expected error, got %s", c.name, sql)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if sql != c.sql || !reflect.DeepEqual(args, c.args) {
+			t.Fatalf("%s: expected %s %v, got %s %v", c.name, c.sql, c.args, sql, args)
+		}
+	}
+}
+
+type keysetEntry struct {
+	ID    int64 `db:"id"`
+	Score int64 `db:"score"`
+}
+
+func TestSelectPageCursors(t *testing.T) {
+	q := pgdaotest.NewDAO(pgdaotest.NewStore(), "entries")
+	// scores 2, 2, 1, 1, 0 in the order of (score DESC, id)
+	for i := int64(0); i < 5; i++ {
+		if _, err := q.New().Create(keysetEntry{Score: (5 - i) / 2}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	columns := []pg.KeysetColumn{{Name: "score", Desc: true}, {Name: "id"}}
+	ids := func(list []keysetEntry) []int64 {
+		var result []int64
+		for _, e := range list {
+			result = append(result, e.ID)
+		}
+		return result
+	}
+	page := func(cursor string) ([]int64, pg.KeysetPage) {
+		var list []keysetEntry
+		p, err := pg.SelectPage(q.New(), &list, pg.KeysetParams{Columns: columns, Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("cursor %q: %v", cursor, err)
+		}
+		return ids(list), p
+	}
+
+	first, p1 := page("")
+	second, p2 := page(p1.Next)
+	third, p3 := page(p2.Next)
+	back, pb := page(p3.Prev)
+
+	cases := []struct {
+		name     string
+		got      []int64
+		expected []int64
+		next     bool
+		prev     bool
+		page     pg.KeysetPage
+	}{
+		{name: "first", got: first, expected: []int64{1, 2}, next: true, page: p1},
+		{name: "second", got: second, expected: []int64{3, 4}, next: true, prev: true, page: p2},
+		{name: "last", got: third, expected: []int64{5}, prev: true, page: p3},
+		{name: "back", got: back, expected: []int64{3, 4}, next: true, prev: true, page: pb},
+	}
+	for _, c := range cases {
+		if !reflect.DeepEqual(c.got, c.expected) {
+			t.Fatalf("%s: expected ids %v, got %v", c.name, c.expected, c.got)
+		}
+		if (c.page.Next != "") != c.next || (c.page.Prev != "") != c.prev {
+			t.Fatalf("%s: unexpected cursors %+v", c.name, c.page)
+		}
+	}
+
+	var list []keysetEntry
+	for _, cursor := range []string{"not base64!", "e30", p1.Next[:len(p1.Next)-2]} {
+		_, err := pg.SelectPage(q.New(), &list, pg.KeysetParams{Columns: columns, Cursor: cursor})
+		if errors.Cause(err) != pg.ErrInvalidCursor {
+			t.Fatalf("cursor %q: expected ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+	_, err := pg.SelectPage(q.New(), &list, pg.KeysetParams{Columns: columns[:1], Cursor: p1.Next})
+	if errors.Cause(err) != pg.ErrInvalidCursor {
+		t.Fatalf("other columns: expected ErrInvalidCursor, got %v", err)
+	}
+}
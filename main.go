@@ -3,7 +3,9 @@ package pg_dao
 import (
 	"context"
 	"database/sql"
+	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	"gitlab.com/distributed_lab/kit/pgdb"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 )
@@ -11,11 +13,31 @@ import (
 const (
 	IdColumn        = "id"
 	CreatedAtColumn = "created_at"
+	UpdatedAtColumn = "updated_at"
 	OrderAscending  = "asc"
 	OrderDescending = "desc"
 )
 
-var ErrNotFound = errors.New("record not found")
+// Operation describes the kind of operation executed by DAO.
+type Operation string
+
+const (
+	OpCreate      Operation = "create"
+	OpGet         Operation = "get"
+	OpSelect      Operation = "select"
+	OpUpdate      Operation = "update"
+	OpDelete      Operation = "delete"
+	OpTransaction Operation = "transaction"
+)
+
+var (
+	ErrNotFound     = errors.New("record not found")
+	ErrStaleVersion = errors.New("record version is stale")
+	// ErrUnfiltered is returned by Update and Delete without conditions unless AllowFullTable is called.
+	ErrUnfiltered = errors.New("update or delete without conditions")
+	// ErrInvalidCursor is the cause of SelectPage errors for cursors it has not issued for the same columns.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
 
 // A DAO describes main methods for common data access object.
 // Notice that you should use Clone() to create new session and New() to use the same.
@@ -26,11 +48,17 @@ type DAO interface {
 
 	Create(dto interface{}) (int64, error)
 	CreateCtx(ctx context.Context, dto interface{}) (int64, error)
+	BulkCreate(dtos interface{}) ([]int64, error)
+	BulkCreateCtx(ctx context.Context, dtos interface{}) ([]int64, error)
 
 	FilterByID(id int64) DAO
+	FilterOnlyAfter(time time.Time) DAO
+	FilterOnlyBefore(time time.Time) DAO
 	FilterGreater(col string, val interface{}) DAO
 	FilterLess(col string, val interface{}) DAO
 	FilterByColumn(col string, val interface{}) DAO
+	Filter(cond sq.Sqlizer) DAO
+	Search(cols []string, query string, opts SearchOptions) DAO
 
 	Get(dto interface{}) (bool, error)
 	GetCtx(ctx context.Context, dto interface{}) (bool, error)
@@ -43,15 +71,33 @@ type DAO interface {
 	OrderByAsc(col string) DAO
 
 	UpdateWhereID(id int64) DAO
+	UpdateWhereVersion(version int64) DAO
 	UpdateColumn(col string, val interface{}) DAO
+	UpdateDTO(dto interface{}) DAO
+	UpdateExpr(col string, expr sq.Sqlizer) DAO
+	Increment(col string, n interface{}) DAO
+	Decrement(col string, n interface{}) DAO
+	ArrayAppend(col string, val interface{}) DAO
+	ArrayRemove(col string, val interface{}) DAO
+
+	UpdateWhere(cond sq.Sqlizer) DAO
 
 	Update() error
 	UpdateCtx(ctx context.Context) error
+	BulkUpdate() (int64, error)
+	BulkUpdateCtx(ctx context.Context) (int64, error)
 
 	DeleteWhereVal(col string, val interface{}) DAO
 	DeleteWhereID(id int64) DAO
+	DeleteWhere(cond sq.Sqlizer) DAO
 	Delete() error
 	DeleteCtx(ctx context.Context) error
+	BulkDelete() (int64, error)
+	BulkDeleteCtx(ctx context.Context) (int64, error)
+
+	AllowFullTable() DAO
+
+	AuditHistory(ctx context.Context, id int64) ([]AuditEntry, error)
 
 	Page(params pgdb.OffsetPageParams, column string) DAO
 	Cursor(params pgdb.CursorPageParams, column string) DAO
@@ -60,6 +106,11 @@ type DAO interface {
 	TransactionSerializable(fn func(q DAO) error) error
 	TransactionWithLevel(level sql.IsolationLevel, fn func(q DAO) error) error
 
+	Validate(ctx context.Context, dto interface{}) error
+
+	ToSQL() (query string, args []interface{}, err error)
+	DryRun() DAO
+
 	ExecRaw(func(raw *pgdb.DB) error) error
 	ExecRawCtx(ctx context.Context, fn func(ctx context.Context, raw *pgdb.DB) error) error
 }
@@ -0,0 +1,428 @@
+package pg_dao
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// Options of the `db` struct tag. DTO fields are mapped to columns by `db` tags both for reads and writes,
+// untagged fields are mapped to their lower-cased names like sqlx does.
+//
+// Enums are declared as named string types, e.g. `type Mood string`. Their values are sent as text parameters
+// that Postgres casts to the enum type of the column, so they need no tag option and work with text columns too.
+// Slices of them are stored as arrays and work with enum array columns.
+const (
+	// TagReadonly marks a column that is scanned but never written, e.g. `db:"id,readonly"`.
+	TagReadonly = "readonly"
+	// TagOmitEmpty marks a column that is not written when the field has zero value, e.g. `db:"name,omitempty"`.
+	TagOmitEmpty = "omitempty"
+	// TagInline maps fields of a nested struct as fields of the parent, e.g. `db:",inline"`.
+	// Embedded structs without a column name are always inlined.
+	TagInline = "inline"
+	// TagJSON stores the field as json or jsonb, e.g. `db:"meta,json"`. Fields of map and struct types
+	// and json.RawMessage are stored as json by default, unless the type implements driver.Valuer
+	// or sql.Scanner or is time.Time.
+	TagJSON = "json"
+	// TagArray stores the slice field as an array, see Array. Fields of slice types other than
+	// byte slices are stored as arrays by default.
+	TagArray = "array"
+	// TagHstore stores the field of map[string]string or map[string]*string type as hstore.
+	TagHstore = "hstore"
+)
+
+type codec int
+
+const (
+	codecNone codec = iota
+	codecJSON
+	codecArray
+	codecHstore
+)
+
+var (
+	scannerType    = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType     = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	timeType       = reflect.TypeOf(time.Time{})
+	bytesType      = reflect.TypeOf([]byte(nil))
+	hstoreType     = reflect.TypeOf(Hstore(nil))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+type field struct {
+	column    string
+	index     []int
+	typ       reflect.Type
+	readonly  bool
+	omitempty bool
+	codec     codec
+}
+
+// mapping describes columns of a DTO type.
+type mapping struct {
+	fields []field
+	// writes are fields written on insert and update, either by `db` or by `structs` tags.
+	writes []field
+	// shadow is a flat struct scanned by sqlx instead of the DTO when sqlx would map
+	// columns to fields differently, nil otherwise.
+	shadow reflect.Type
+}
+
+type mappingKey struct {
+	typ         reflect.Type
+	structsTags bool
+}
+
+// mappings caches mapping by mappingKey.
+var mappings sync.Map
+
+// WithStructsTags makes Create and UpdateDTO honor `structs` tags of fields that have them:
+// `structs:"name"` renames the column and `structs:",omitempty"` skips zero values. Fields tagged
+// `structs:"-"` are never written, with or without the option. Intended for DTOs written before
+// `db` tags were used for writes.
+func WithStructsTags() Option {
+	return func(o *options) {
+		o.structsTags = true
+	}
+}
+
+// ColumnValues returns column values Create writes for dto, which is a struct, a pointer to struct
+// or map[string]interface{} that is returned as is. Values are not encoded with json, array or hstore codecs.
+func ColumnValues(dto interface{}) (map[string]interface{}, error) {
+	return columnValues(dto, false, true, false)
+}
+
+func columnValues(dto interface{}, structsTags, insert, encode bool) (map[string]interface{}, error) {
+	if m, ok := dto.(map[string]interface{}); ok {
+		values := make(map[string]interface{}, len(m))
+		for col, val := range m {
+			values[col] = val
+		}
+		return values, nil
+	}
+
+	v := reflect.ValueOf(dto)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, errors.From(errors.New("dto must be a struct or map"), map[string]interface{}{
+			"type": reflect.TypeOf(dto),
+		})
+	}
+
+	m := mappingOf(v.Type(), structsTags)
+	values := make(map[string]interface{}, len(m.writes))
+	for _, f := range m.writes {
+		fv, ok := fieldByIndex(v, f.index, false)
+		if !ok || f.omitempty && fv.IsZero() || insert && f.column == IdColumn && fv.IsZero() {
+			continue
+		}
+		if encode {
+			values[f.column] = f.codec.encode(fv)
+		} else {
+			values[f.column] = fv.Interface()
+		}
+	}
+	return values, nil
+}
+
+func mappingOf(t reflect.Type, structsTags bool) *mapping {
+	key := mappingKey{typ: t, structsTags: structsTags}
+	if m, ok := mappings.Load(key); ok {
+		return m.(*mapping)
+	}
+
+	m := &mapping{}
+	seen := make(map[string]bool)
+	flat := m.walk(t, nil, seen, true)
+
+	for _, f := range m.fields {
+		if f.readonly {
+			continue
+		}
+		// fields excluded from writes with `structs:"-"` stay excluded without WithStructsTags
+		if tag, ok := t.FieldByIndex(f.index).Tag.Lookup("structs"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if structsTags && parts[0] != "" {
+				f.column = parts[0]
+			}
+			f.omitempty = f.omitempty || structsTags && hasOption(parts, TagOmitEmpty)
+		}
+		m.writes = append(m.writes, f)
+	}
+
+	for _, f := range m.fields {
+		flat = flat && f.codec == codecNone
+	}
+	if !flat {
+		fields := make([]reflect.StructField, len(m.fields))
+		for i, f := range m.fields {
+			typ := f.typ
+			if f.codec != codecNone {
+				typ = bytesType
+			}
+			fields[i] = reflect.StructField{
+				Name: fmt.Sprintf("F%d", i),
+				Type: typ,
+				Tag:  reflect.StructTag(`db:"` + f.column + `"`),
+			}
+		}
+		m.shadow = reflect.StructOf(fields)
+	}
+
+	actual, _ := mappings.LoadOrStore(key, m)
+	return actual.(*mapping)
+}
+
+// walk collects fields of t, the first field mapped to a column wins. It reports whether
+// sqlx maps the columns to the same fields.
+func (m *mapping) walk(t reflect.Type, index []int, seen map[string]bool, flat bool) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		parts := strings.Split(f.Tag.Get("db"), ",")
+		name := parts[0]
+		if name == "-" {
+			continue
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && name == "" && (f.Anonymous || hasOption(parts, TagInline)) && !isScalar(f.Type) {
+			flat = m.walk(ft, fieldIndex, seen, flat && f.Anonymous)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		m.fields = append(m.fields, field{
+			column:    name,
+			index:     fieldIndex,
+			typ:       f.Type,
+			readonly:  hasOption(parts, TagReadonly),
+			omitempty: hasOption(parts, TagOmitEmpty),
+			codec:     fieldCodec(f.Type, parts),
+		})
+	}
+	return flat
+}
+
+func fieldCodec(t reflect.Type, parts []string) codec {
+	base := t
+	for base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+
+	switch {
+	case hasOption(parts, TagJSON):
+		return codecJSON
+	case hasOption(parts, TagArray):
+		return codecArray
+	case hasOption(parts, TagHstore):
+		return codecHstore
+	case base == rawMessageType:
+		return codecJSON
+	case isScalar(t) || isScalar(base) || isBytes(t):
+		return codecNone
+	case t.Kind() == reflect.Slice:
+		return codecArray
+	case t.Kind() == reflect.Map, base.Kind() == reflect.Struct:
+		return codecJSON
+	}
+	return codecNone
+}
+
+// encode returns the value written to the column.
+func (c codec) encode(v reflect.Value) interface{} {
+	switch c {
+	case codecJSON:
+		return JSON{V: v.Interface()}
+	case codecArray:
+		return Array(v.Interface())
+	case codecHstore:
+		if v.IsNil() {
+			return Hstore(nil)
+		}
+		h := make(Hstore, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			val := reflect.Indirect(iter.Value())
+			if !val.IsValid() {
+				h[iter.Key().String()] = nil
+				continue
+			}
+			s := val.String()
+			h[iter.Key().String()] = &s
+		}
+		return h
+	}
+	return v.Interface()
+}
+
+// decode sets v to the value scanned from the column, nil data is NULL.
+func (c codec) decode(v reflect.Value, data []byte) error {
+	var src interface{}
+	if data != nil {
+		src = data
+	}
+
+	switch c {
+	case codecJSON:
+		return (&JSON{V: v.Addr().Interface()}).Scan(src)
+	case codecArray:
+		return Array(v.Addr().Interface()).Scan(src)
+	case codecHstore:
+		var h Hstore
+		if err := h.Scan(src); err != nil {
+			return err
+		}
+		if h == nil || v.Type().ConvertibleTo(hstoreType) || hstoreType.ConvertibleTo(v.Type()) {
+			if h == nil {
+				v.Set(reflect.Zero(v.Type()))
+			} else {
+				v.Set(reflect.ValueOf(h).Convert(v.Type()))
+			}
+			return nil
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(h))
+		for key, val := range h {
+			var s string
+			if val != nil {
+				s = *val
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), reflect.ValueOf(s).Convert(v.Type().Elem()))
+		}
+		v.Set(m)
+		return nil
+	}
+	return errors.Errorf("unsupported codec %d", c)
+}
+
+// isScalar reports whether values of t are scanned and written as a single column.
+func isScalar(t reflect.Type) bool {
+	return t.Implements(valuerType) || reflect.PtrTo(t).Implements(scannerType) || t == timeType
+}
+
+// isBytes reports whether t is a byte slice, e.g. []byte or a named type declared as one, written as bytea.
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// fieldByIndex returns the nested field of struct v. Nil pointers on the way are allocated if alloc is set,
+// otherwise false is returned.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// scanDest returns the destination sqlx has to scan into and a function copying scanned values to dest.
+// dest is a pointer to a struct or to a slice of structs or of pointers to structs.
+func scanDest(dest interface{}) (interface{}, func() error) {
+	nop := func() error { return nil }
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return dest, nop
+	}
+
+	t := v.Elem().Type()
+	list := t.Kind() == reflect.Slice
+	if list {
+		t = t.Elem()
+	}
+	ptr := t.Kind() == reflect.Ptr
+	if ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || isScalar(t) {
+		return dest, nop
+	}
+
+	m := mappingOf(t, false)
+	if m.shadow == nil {
+		return dest, nop
+	}
+
+	if !list {
+		shadow := reflect.New(m.shadow)
+		return shadow.Interface(), func() error {
+			return m.copy(v.Elem(), shadow.Elem())
+		}
+	}
+
+	shadows := reflect.New(reflect.SliceOf(m.shadow))
+	return shadows.Interface(), func() error {
+		rows := shadows.Elem()
+		result := reflect.MakeSlice(v.Elem().Type(), rows.Len(), rows.Len())
+		for i := 0; i < rows.Len(); i++ {
+			item := result.Index(i)
+			if ptr {
+				item.Set(reflect.New(t))
+				item = item.Elem()
+			}
+			if err := m.copy(item, rows.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Elem().Set(result)
+		return nil
+	}
+}
+
+// copy sets fields of dest to values of the shadow struct, decoding them if needed.
+func (m *mapping) copy(dest, shadow reflect.Value) error {
+	for i, f := range m.fields {
+		fv, _ := fieldByIndex(dest, f.index, true)
+		if f.codec == codecNone {
+			fv.Set(shadow.Field(i))
+			continue
+		}
+		if err := f.codec.decode(fv, shadow.Field(i).Bytes()); err != nil {
+			return errors.Wrap(err, "failed to decode column", map[string]interface{}{"column": f.column})
+		}
+	}
+	return nil
+}
+
+func hasOption(parts []string, option string) bool {
+	for _, p := range parts[1:] {
+		if p == option {
+			return true
+		}
+	}
+	return false
+}
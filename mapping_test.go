@@ -0,0 +1,161 @@
+package pg_dao
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type rawBytes []byte
+
+type codecMood string
+
+type codecMeta struct {
+	Source string `json:"source"`
+}
+
+type codecEntry struct {
+	Data  []byte          `db:"data"`
+	Blob  rawBytes        `db:"blob"`
+	Raw   json.RawMessage `db:"raw"`
+	Tags  []string        `db:"tags"`
+	Bytes []byte          `db:"bytes,json"`
+}
+
+type defaultsEntry struct {
+	Meta    codecMeta      `db:"meta"`
+	Note    *codecMeta     `db:"note"`
+	At      time.Time      `db:"at"`
+	Seen    *time.Time     `db:"seen"`
+	Name    sql.NullString `db:"name"`
+	Slots   TstzRange      `db:"slots"`
+	Ids     *Int8Range     `db:"ids"`
+	Mood    codecMood      `db:"mood"`
+	Moods   []codecMood    `db:"moods"`
+	Options map[string]int `db:"options"`
+}
+
+func TestFieldCodec(t *testing.T) {
+	m := mappingOf(reflect.TypeOf(codecEntry{}), false)
+
+	expected := map[string]codec{
+		"data":  codecNone,
+		"blob":  codecNone,
+		"raw":   codecJSON,
+		"tags":  codecArray,
+		"bytes": codecJSON,
+	}
+	for _, f := range m.fields {
+		if f.codec != expected[f.column] {
+			t.Fatalf("%s: expected codec %d, got %d", f.column, expected[f.column], f.codec)
+		}
+	}
+}
+
+func TestFieldCodecDefaults(t *testing.T) {
+	m := mappingOf(reflect.TypeOf(defaultsEntry{}), false)
+
+	expected := map[string]codec{
+		"meta":    codecJSON,
+		"note":    codecJSON,
+		"at":      codecNone,
+		"seen":    codecNone,
+		"name":    codecNone,
+		"slots":   codecNone,
+		"ids":     codecNone,
+		"mood":    codecNone,
+		"moods":   codecArray,
+		"options": codecJSON,
+	}
+	if len(m.fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %d", len(expected), len(m.fields))
+	}
+	for _, f := range m.fields {
+		if f.codec != expected[f.column] {
+			t.Fatalf("%s: expected codec %d, got %d", f.column, expected[f.column], f.codec)
+		}
+	}
+}
+
+func TestEnumValues(t *testing.T) {
+	values, err := columnValues(defaultsEntry{
+		Mood:  "happy",
+		Moods: []codecMood{"happy", "sad"},
+		Meta:  codecMeta{Source: "api"},
+	}, false, true, true)
+	if err != nil {
+		t.Fatalf("columnValues: %v", err)
+	}
+
+	cases := []struct {
+		column   string
+		expected driver.Value
+	}{
+		{column: "mood", expected: "happy"},
+		{column: "moods", expected: `{"happy","sad"}`},
+		{column: "meta", expected: `{"source":"api"}`},
+		{column: "note", expected: nil},
+	}
+	for _, c := range cases {
+		val := values[c.column]
+		if valuer, ok := val.(driver.Valuer); ok {
+			val, err = valuer.Value()
+		} else {
+			val, err = driver.DefaultParameterConverter.ConvertValue(val)
+		}
+		if err != nil {
+			t.Fatalf("%s: value: %v", c.column, err)
+		}
+		if val != c.expected {
+			t.Fatalf("%s: expected %v, got %v", c.column, c.expected, val)
+		}
+	}
+
+	var moods []codecMood
+	if err := Array(&moods).Scan([]byte("{sad,happy}")); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if !reflect.DeepEqual(moods, []codecMood{"sad", "happy"}) {
+		t.Fatalf("scan: expected [sad happy], got %v", moods)
+	}
+}
+
+func TestRawMessageRoundTrip(t *testing.T) {
+	cases := []struct {
+		name     string
+		raw      json.RawMessage
+		expected interface{}
+	}{
+		{name: "object", raw: json.RawMessage(`{"a": [1, 2]}`), expected: `{"a":[1,2]}`},
+		{name: "null", raw: nil, expected: nil},
+	}
+
+	for _, c := range cases {
+		values, err := columnValues(codecEntry{Raw: c.raw}, false, true, true)
+		if err != nil {
+			t.Fatalf("%s: columnValues: %v", c.name, err)
+		}
+		val, err := values["raw"].(JSON).Value()
+		if err != nil {
+			t.Fatalf("%s: value: %v", c.name, err)
+		}
+		if val != c.expected {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, val)
+		}
+
+		var got codecEntry
+		var data []byte
+		if val != nil {
+			data = []byte(val.(string))
+		}
+		if err := codecJSON.decode(reflect.ValueOf(&got).Elem().FieldByName("Raw"), data); err != nil {
+			t.Fatalf("%s: decode: %v", c.name, err)
+		}
+		if string(got.Raw) != string(data) {
+			t.Fatalf("%s: expected %s, got %s", c.name, data, got.Raw)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package pg_dao
+
+import (
+	"context"
+	"database/sql"
+	goerr "errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// ErrorClass is a low-cardinality description of an operation error suitable for metric labels.
+type ErrorClass string
+
+const (
+	ErrorClassNone          ErrorClass = "none"
+	ErrorClassNotFound      ErrorClass = "not_found"
+	ErrorClassStaleVersion  ErrorClass = "stale_version"
+	ErrorClassCanceled      ErrorClass = "canceled"
+	ErrorClassTimeout       ErrorClass = "timeout"
+	ErrorClassConstraint    ErrorClass = "constraint"
+	ErrorClassSerialization ErrorClass = "serialization"
+	ErrorClassDryRun        ErrorClass = "dry_run"
+	ErrorClassOther         ErrorClass = "other"
+)
+
+// ClassifyError returns the class of an error returned by DAO.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassNone
+	}
+
+	cause := errors.Cause(err)
+	switch {
+	case cause == ErrNotFound || cause == sql.ErrNoRows:
+		return ErrorClassNotFound
+	case cause == ErrStaleVersion:
+		return ErrorClassStaleVersion
+	case goerr.As(cause, new(*DryRunStatement)):
+		return ErrorClassDryRun
+	case goerr.Is(cause, context.Canceled):
+		return ErrorClassCanceled
+	case goerr.Is(cause, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	}
+
+	if pqErr, ok := cause.(*pq.Error); ok {
+		switch pqErr.Code.Class() {
+		case "23":
+			return ErrorClassConstraint
+		case "40":
+			return ErrorClassSerialization
+		case "57":
+			if pqErr.Code == "57014" {
+				return ErrorClassTimeout
+			}
+		}
+	}
+	return ErrorClassOther
+}
+
+// Observation describes a finished DAO operation.
+type Observation struct {
+	Table      string
+	Operation  Operation
+	Duration   time.Duration
+	ErrorClass ErrorClass
+}
+
+// An Observer is notified after every Get, Select, Create, Update, Delete and transaction.
+type Observer interface {
+	Observe(ctx context.Context, o Observation)
+}
+
+// NopObserver is an Observer that does nothing, used by default.
+type NopObserver struct{}
+
+func (NopObserver) Observe(context.Context, Observation) {}
+
+// WithObserver sets the Observer notified about DAO operations.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+func (d *dao) observe(ctx context.Context, op Operation, start time.Time, err *error) {
+	d.opts.observer.Observe(ctx, Observation{
+		Table:      d.tableName,
+		Operation:  op,
+		Duration:   time.Since(start),
+		ErrorClass: ClassifyError(*err),
+	})
+}
+
+// DefaultBuckets are upper bounds of Metrics duration histogram buckets used by default.
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// Metrics is an in-process Observer collecting operation counters and duration histograms.
+// Use Snapshot to export collected values to a metrics system.
+type Metrics struct {
+	buckets []time.Duration
+	mu      sync.Mutex
+	series  map[SeriesKey]*Series
+}
+
+// SeriesKey identifies a single Metrics series.
+type SeriesKey struct {
+	Table      string
+	Operation  Operation
+	ErrorClass ErrorClass
+}
+
+// Series holds values collected for a single SeriesKey.
+// Buckets are cumulative: Buckets[i] is the number of operations not longer than Bounds[i].
+type Series struct {
+	SeriesKey
+	Count   uint64
+	Sum     time.Duration
+	Bounds  []time.Duration
+	Buckets []uint64
+}
+
+// NewMetrics creates Metrics with provided histogram buckets upper bounds, DefaultBuckets if none provided.
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]time.Duration(nil), buckets...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	return &Metrics{
+		buckets: sorted,
+		series:  make(map[SeriesKey]*Series),
+	}
+}
+
+func (m *Metrics) Observe(_ context.Context, o Observation) {
+	key := SeriesKey{
+		Table:      o.Table,
+		Operation:  o.Operation,
+		ErrorClass: o.ErrorClass,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.series[key]
+	if !ok {
+		s = &Series{
+			SeriesKey: key,
+			Bounds:    m.buckets,
+			Buckets:   make([]uint64, len(m.buckets)),
+		}
+		m.series[key] = s
+	}
+
+	s.Count++
+	s.Sum += o.Duration
+	for i, bound := range m.buckets {
+		if o.Duration <= bound {
+			s.Buckets[i]++
+		}
+	}
+}
+
+// Snapshot returns a copy of all collected series.
+func (m *Metrics) Snapshot() []Series {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]Series, 0, len(m.series))
+	for _, s := range m.series {
+		c := *s
+		c.Buckets = append([]uint64(nil), s.Buckets...)
+		result = append(result, c)
+	}
+	return result
+}
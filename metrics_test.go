@@ -0,0 +1,101 @@
+package pg_dao_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	pg "github.com/olegfomenko/pg-dao"
+	"github.com/olegfomenko/pg-dao/pgdaotest"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected pg.ErrorClass
+	}{
+		{name: "nil", err: nil, expected: pg.ErrorClassNone},
+		{name: "not found", err: errors.Wrap(pg.ErrNotFound, "failed to get"), expected: pg.ErrorClassNotFound},
+		{name: "no rows", err: sql.ErrNoRows, expected: pg.ErrorClassNotFound},
+		{name: "stale version", err: pg.ErrStaleVersion, expected: pg.ErrorClassStaleVersion},
+		{name: "dry run", err: &pg.DryRunStatement{SQL: "SELECT 1"}, expected: pg.ErrorClassDryRun},
+		{name: "canceled", err: errors.Wrap(context.Canceled, "failed"), expected: pg.ErrorClassCanceled},
+		{name: "deadline", err: context.DeadlineExceeded, expected: pg.ErrorClassTimeout},
+		{name: "statement timeout", err: &pq.Error{Code: "57014"}, expected: pg.ErrorClassTimeout},
+		{name: "admin shutdown", err: &pq.Error{Code: "57P01"}, expected: pg.ErrorClassOther},
+		{name: "unique violation", err: errors.Wrap(&pq.Error{Code: "23505"}, "failed"), expected: pg.ErrorClassConstraint},
+		{name: "serialization", err: &pq.Error{Code: "40001"}, expected: pg.ErrorClassSerialization},
+		{name: "other", err: errors.New("boom"), expected: pg.ErrorClassOther},
+	}
+	for _, c := range cases {
+		if got := pg.ClassifyError(c.err); got != c.expected {
+			t.Fatalf("%s: expected %s, got %s", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := pg.NewMetrics(10*time.Millisecond, time.Millisecond)
+	key := pg.SeriesKey{Table: "entries", Operation: pg.OpGet, ErrorClass: pg.ErrorClassNone}
+	for _, d := range []time.Duration{time.Millisecond, 5 * time.Millisecond, time.Second} {
+		m.Observe(context.Background(), pg.Observation{
+			Table:      key.Table,
+			Operation:  key.Operation,
+			Duration:   d,
+			ErrorClass: key.ErrorClass,
+		})
+	}
+
+	snapshot := m.Snapshot()
+	expected := []pg.Series{{
+		SeriesKey: key,
+		Count:     3,
+		Sum:       time.Second + 6*time.Millisecond,
+		Bounds:    []time.Duration{time.Millisecond, 10 * time.Millisecond},
+		Buckets:   []uint64{1, 2},
+	}}
+	if !reflect.DeepEqual(snapshot, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, snapshot)
+	}
+
+	// snapshots are copies
+	snapshot[0].Buckets[0] = 100
+	if got := m.Snapshot()[0].Buckets[0]; got != 1 {
+		t.Fatalf("snapshot shares buckets with metrics, got %d", got)
+	}
+}
+
+func TestObserverClassifiesOperations(t *testing.T) {
+	m := pg.NewMetrics()
+	q := pgdaotest.NewRecorder().ReturnRowsAffected(1, 0).DAO("entries", pg.WithObserver(m))
+
+	// a missing row is not an error of Get
+	var e entry
+	if ok, err := q.FilterByID(1).Get(&e); ok || err != nil {
+		t.Fatalf("get: expected no row, got %v, %v", ok, err)
+	}
+	if err := q.New().UpdateWhereID(1).UpdateColumn("name", "a").Update(); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := q.New().UpdateWhereID(2).UpdateColumn("name", "a").Update(); errors.Cause(err) != pg.ErrNotFound {
+		t.Fatalf("update: expected ErrNotFound, got %v", err)
+	}
+
+	counts := make(map[pg.SeriesKey]uint64)
+	for _, s := range m.Snapshot() {
+		counts[s.SeriesKey] = s.Count
+	}
+	expected := map[pg.SeriesKey]uint64{
+		{Table: "entries", Operation: pg.OpGet, ErrorClass: pg.ErrorClassNone}:        1,
+		{Table: "entries", Operation: pg.OpUpdate, ErrorClass: pg.ErrorClassNone}:     1,
+		{Table: "entries", Operation: pg.OpUpdate, ErrorClass: pg.ErrorClassNotFound}: 1,
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("expected %v, got %v", expected, counts)
+	}
+}
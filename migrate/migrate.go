@@ -0,0 +1,408 @@
+// Package migrate applies versioned SQL migrations to PostgreSQL.
+//
+// Migrations are files named <version>_<name>.up.sql and <version>_<name>.down.sql, where version
+// is a positive integer. Every migration runs in its own transaction unless its up file contains
+// the "-- migrate:notransaction" line. Applied versions are tracked in a table and
+// concurrent migrators are serialized with an advisory lock.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// DefaultTable is the table applied versions are tracked in.
+const DefaultTable = "schema_migrations"
+
+// NoTransactionDirective marks a migration to run outside of a transaction, e.g. for CREATE INDEX CONCURRENTLY.
+const NoTransactionDirective = "-- migrate:notransaction"
+
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned migration.
+type Migration struct {
+	Version       int64
+	Name          string
+	Up            string
+	Down          string
+	NoTransaction bool
+}
+
+// Status describes whether a migration has been applied.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// An Option configures Migrator.
+type Option func(*Migrator)
+
+// WithTable sets the table applied versions are tracked in.
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// Migrator applies migrations to the database.
+type Migrator struct {
+	db         *pgdb.DB
+	table      string
+	migrations []Migration
+}
+
+// New reads migrations from the root of fsys.
+func New(db *pgdb.DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	m := &Migrator{
+		db:    db,
+		table: DefaultTable,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	migrations, err := read(fsys)
+	if err != nil {
+		return nil, err
+	}
+	m.migrations = migrations
+	return m, nil
+}
+
+// NewFromDir reads migrations from the directory.
+func NewFromDir(db *pgdb.DB, dir string, opts ...Option) (*Migrator, error) {
+	return New(db, os.DirFS(dir), opts...)
+}
+
+func read(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read migrations")
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid migration version", map[string]interface{}{"file": entry.Name()})
+		}
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read migration", map[string]interface{}{"file": entry.Name()})
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != match[2] {
+			return nil, errors.From(errors.New("migrations with the same version have different names"), map[string]interface{}{
+				"version": version,
+			})
+		}
+
+		if match[3] == "up" {
+			mig.Up = string(script)
+			mig.NoTransaction = hasDirective(mig.Up)
+		} else {
+			mig.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, errors.From(errors.New("migration has no up file"), map[string]interface{}{
+				"version": mig.Version,
+			})
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func hasDirective(script string) bool {
+	for _, line := range strings.Split(script, "\n") {
+		if strings.TrimSpace(line) == NoTransactionDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back n last applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n < 0 {
+		return errors.From(errors.New("number of migrations to roll back is negative"), map[string]interface{}{
+			"n": n,
+		})
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedDesc(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if n < len(versions) {
+			versions = versions[:n]
+		}
+		return m.rollback(ctx, conn, versions)
+	})
+}
+
+// To migrates the database up or down to the version: migrations up to it are applied
+// and applied migrations above it are rolled back.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok || mig.Version > version {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+		}
+
+		versions, err := m.appliedDesc(ctx, conn)
+		if err != nil {
+			return err
+		}
+		var above []int64
+		for _, v := range versions {
+			if v > version {
+				above = append(above, v)
+			}
+		}
+		return m.rollback(ctx, conn, above)
+	})
+}
+
+// Status returns all known and applied migrations ordered by version. Applied versions are read
+// without the advisory lock, so Status does not wait for running migrations.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	db := m.db.RawDB()
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", m.table).Scan(&exists); err != nil {
+		return nil, errors.Wrap(err, "failed to check migrations table")
+	}
+	applied := make(map[int64]appliedMigration)
+	if exists {
+		var err error
+		if applied, err = m.applied(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if a, ok := applied[mig.Version]; ok {
+			s.Applied, s.AppliedAt = true, &a.at
+			delete(applied, mig.Version)
+		}
+		result = append(result, s)
+	}
+	// versions applied but missing from the source
+	for version, a := range applied {
+		at := a.at
+		result = append(result, Status{Version: version, Name: a.name, Applied: true, AppliedAt: &at})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// withLock runs fn on a dedicated connection holding the advisory lock of the migrations table.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.RawDB().Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get connection")
+	}
+	defer conn.Close()
+
+	key := m.lockKey()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		return errors.Wrap(err, "failed to acquire migrations lock")
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT        NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`, m.table))
+	if err != nil {
+		return errors.Wrap(err, "failed to create migrations table")
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("pg-dao/migrate:" + m.table))
+	return int64(h.Sum64())
+}
+
+type appliedMigration struct {
+	name string
+	at   time.Time
+}
+
+// queryer is either the connection holding the lock or the pool.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn queryer) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, name, applied_at FROM %s", m.table))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select applied migrations")
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var (
+			version int64
+			a       appliedMigration
+		)
+		if err := rows.Scan(&version, &a.name, &a.at); err != nil {
+			return nil, errors.Wrap(err, "failed to scan applied migration")
+		}
+		applied[version] = a
+	}
+	return applied, errors.Wrap(rows.Err(), "failed to select applied migrations")
+}
+
+func (m *Migrator) appliedDesc(ctx context.Context, conn *sql.Conn) ([]int64, error) {
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+	return versions, nil
+}
+
+func (m *Migrator) rollback(ctx context.Context, conn *sql.Conn, versions []int64) error {
+	for _, version := range versions {
+		mig, ok := m.find(version)
+		if !ok {
+			return errors.From(errors.New("applied migration is missing from the source"), map[string]interface{}{
+				"version": version,
+			})
+		}
+		if mig.Down == "" {
+			return errors.From(errors.New("migration has no down file"), map[string]interface{}{
+				"version": version,
+			})
+		}
+		if err := m.apply(ctx, conn, mig, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+// apply runs the up or down script of the migration and records the change of the applied versions.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, up bool) error {
+	script := mig.Down
+	track := fmt.Sprintf("DELETE FROM %s WHERE version = $1", m.table)
+	trackArgs := []interface{}{mig.Version}
+	if up {
+		script = mig.Up
+		track = fmt.Sprintf("INSERT INTO %s (version, name) VALUES ($1, $2)", m.table)
+		trackArgs = append(trackArgs, mig.Name)
+	}
+	fields := map[string]interface{}{
+		"version": mig.Version,
+		"name":    mig.Name,
+		"up":      up,
+	}
+
+	if mig.NoTransaction {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return errors.Wrap(err, "failed to run migration", fields)
+		}
+		if _, err := conn.ExecContext(ctx, track, trackArgs...); err != nil {
+			return errors.Wrap(err, "failed to track migration", fields)
+		}
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin tx", fields)
+	}
+	// swallowing rollback err, should not affect data consistency
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return errors.Wrap(err, "failed to run migration", fields)
+	}
+	if _, err := tx.ExecContext(ctx, track, trackArgs...); err != nil {
+		return errors.Wrap(err, "failed to track migration", fields)
+	}
+	return errors.Wrap(tx.Commit(), "failed to commit migration", fields)
+}
@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRead(t *testing.T) {
+	cases := []struct {
+		name     string
+		files    map[string]string
+		expected []Migration
+		fails    bool
+	}{
+		{
+			name: "ordered by version",
+			files: map[string]string{
+				"10_add_index.up.sql":     "CREATE INDEX i ON t (a);",
+				"2_create_table.up.sql":   "CREATE TABLE t (a INT);",
+				"2_create_table.down.sql": "DROP TABLE t;",
+				"README.md":               "not a migration",
+				"3_draft.sql":             "SELECT 1;",
+			},
+			expected: []Migration{
+				{Version: 2, Name: "create_table", Up: "CREATE TABLE t (a INT);", Down: "DROP TABLE t;"},
+				{Version: 10, Name: "add_index", Up: "CREATE INDEX i ON t (a);"},
+			},
+		},
+		{
+			name: "no transaction directive",
+			files: map[string]string{
+				"1_concurrent.up.sql": "-- migrate:notransaction\nCREATE INDEX CONCURRENTLY i ON t (a);",
+			},
+			expected: []Migration{{
+				Version:       1,
+				Name:          "concurrent",
+				Up:            "-- migrate:notransaction\nCREATE INDEX CONCURRENTLY i ON t (a);",
+				NoTransaction: true,
+			}},
+		},
+		{
+			name:     "directory",
+			files:    map[string]string{"1_nested.up.sql/file.sql": "SELECT 1;"},
+			expected: []Migration{},
+		},
+		{
+			name:  "down without up",
+			files: map[string]string{"1_a.down.sql": "DROP TABLE t;"},
+			fails: true,
+		},
+		{
+			name: "different names of a version",
+			files: map[string]string{
+				"1_a.up.sql": "SELECT 1;",
+				"1_b.up.sql": "SELECT 2;",
+			},
+			fails: true,
+		},
+		{
+			name:  "version out of range",
+			files: map[string]string{"99999999999999999999_a.up.sql": "SELECT 1;"},
+			fails: true,
+		},
+	}
+
+	for _, c := range cases {
+		fsys := make(fstest.MapFS, len(c.files))
+		for name, data := range c.files {
+			fsys[name] = &fstest.MapFile{Data: []byte(data)}
+		}
+
+		migrations, err := read(fsys)
+		if c.fails {
+			if err == nil {
+				t.Fatalf("%s: expected error, got %+v", c.name, migrations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(migrations, c.expected) {
+			t.Fatalf("%s: expected %+v, got %+v", c.name, c.expected, migrations)
+		}
+	}
+}
+
+func TestHasDirective(t *testing.T) {
+	cases := []struct {
+		script   string
+		expected bool
+	}{
+		{script: "-- migrate:notransaction\nCREATE INDEX CONCURRENTLY i ON t (a);", expected: true},
+		{script: "CREATE INDEX CONCURRENTLY i ON t (a);\n  -- migrate:notransaction  \r\n", expected: true},
+		{script: "CREATE INDEX i ON t (a); -- migrate:notransaction"},
+		{script: "-- migrate:notransactions"},
+		{script: "SELECT '-- migrate:notransaction';"},
+		{script: ""},
+	}
+
+	for _, c := range cases {
+		if got := hasDirective(c.script); got != c.expected {
+			t.Fatalf("%q: expected %v, got %v", c.script, c.expected, got)
+		}
+	}
+}
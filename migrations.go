@@ -0,0 +1,9 @@
+package pg_dao
+
+import "embed"
+
+// Migrations contains SQL migrations for tables required by pg-dao extensions (e.g. audit log).
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id         BIGSERIAL PRIMARY KEY,
+    table_name TEXT        NOT NULL,
+    record_id  BIGINT      NOT NULL,
+    operation  TEXT        NOT NULL,
+    before     JSONB,
+    after      JSONB,
+    actor      TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_record_idx ON audit_log (table_name, record_id);
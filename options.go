@@ -0,0 +1,119 @@
+package pg_dao
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3"
+)
+
+// An Option configures optional DAO behaviour. Options are passed to NewDAO
+// and are shared by every session derived from it with Clone() or New().
+type Option func(*options)
+
+type options struct {
+	createdAt string
+	updatedAt string
+	now       func() time.Time
+	version   string
+	hooks     []Hooks
+	audit     bool
+	redact    map[string]bool
+
+	structsTags bool
+
+	log        *logan.Entry
+	logLevel   logan.Level
+	logArgs    bool
+	redactArgs func(query string, args []interface{}) []interface{}
+
+	slowThreshold time.Duration
+	slowReporter  func(ctx context.Context, q SlowQuery)
+	slowExplain   bool
+
+	observer Observer
+
+	tracer    Tracer
+	commenter bool
+
+	executor Executor
+	inTx     bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		now:      time.Now,
+		logLevel: logan.DebugLevel,
+		observer: NopObserver{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithTimestamps enables automatic timestamps management: createdAt column is filled on Create
+// and updatedAt column is set on every Update unless set with UpdateColumn. Pass an empty name
+// to leave a column untouched.
+func WithTimestamps(createdAt, updatedAt string) Option {
+	return func(o *options) {
+		o.createdAt = createdAt
+		o.updatedAt = updatedAt
+	}
+}
+
+// WithClock sets the time source used for timestamps. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithVersion enables optimistic locking using the provided version column. Every Update checks
+// the version passed with UpdateWhereVersion and increments it, returning ErrStaleVersion
+// if the row has been modified concurrently.
+func WithVersion(col string) Option {
+	return func(o *options) {
+		o.version = col
+	}
+}
+
+// WithLogger enables logging of every executed statement: SQL, arguments count, duration,
+// rows affected and error are passed as logan fields.
+func WithLogger(log *logan.Entry) Option {
+	return func(o *options) {
+		o.log = log
+	}
+}
+
+// WithLogLevel sets the level statements are logged with. Defaults to logan.DebugLevel.
+func WithLogLevel(level logan.Level) Option {
+	return func(o *options) {
+		o.logLevel = level
+	}
+}
+
+// WithLogArgs enables logging of statement arguments, which are omitted by default.
+// If redact is not nil, arguments are logged as returned by it.
+func WithLogArgs(redact func(query string, args []interface{}) []interface{}) Option {
+	return func(o *options) {
+		o.logArgs = true
+		o.redactArgs = redact
+	}
+}
+
+func (o *options) createdAtColumn() string {
+	if o.createdAt == "" {
+		return CreatedAtColumn
+	}
+	return o.createdAt
+}
+
+// WithinTransaction makes DAO treat a transaction of its database as already running, e.g. for a DAO created
+// inside pgdb.DB.Transaction or inside Transaction of another DAO. Writes that need a transaction, like audited
+// ones, run in it instead of starting a nested one, which pgdb does not support.
+func WithinTransaction() Option {
+	return func(o *options) {
+		o.inTx = true
+	}
+}
@@ -0,0 +1,82 @@
+package pgdaotest
+
+import (
+	"reflect"
+
+	pg "github.com/olegfomenko/pg-dao"
+)
+
+// arrayPredicate evaluates array filters over slices the way array operators do: elements are equal
+// if compare reports so, hence NULL elements never match.
+func arrayPredicate(cond pg.ArrayCondition) (predicate, bool) {
+	values, ok := elements(cond.Values)
+	if !ok {
+		return nil, false
+	}
+
+	switch cond.Op {
+	case pg.ArrayOpAny:
+		return func(r row) bool {
+			return hasElem(values, r[cond.Column])
+		}, true
+	case pg.ArrayOpContains, pg.ArrayOpOverlaps, pg.ArrayOpContainedBy:
+		return func(r row) bool {
+			list, _ := elements(r[cond.Column])
+			if list == nil || values == nil {
+				return false
+			}
+			switch cond.Op {
+			case pg.ArrayOpContains:
+				return containsAll(list, values)
+			case pg.ArrayOpContainedBy:
+				return containsAll(values, list)
+			}
+			for _, v := range values {
+				if hasElem(list, v) {
+					return true
+				}
+			}
+			return false
+		}, true
+	}
+	return nil, false
+}
+
+// elements returns elements of a slice or array value, the result is nil for NULL.
+// ok is false for values of other types.
+func elements(val interface{}) (result []interface{}, ok bool) {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch {
+	case !v.IsValid(), v.Kind() == reflect.Ptr, v.Kind() == reflect.Slice && v.IsNil():
+		return nil, true
+	case v.Kind() != reflect.Slice && v.Kind() != reflect.Array, v.Type().Elem().Kind() == reflect.Uint8:
+		return nil, false
+	}
+
+	result = make([]interface{}, v.Len())
+	for i := range result {
+		result[i] = v.Index(i).Interface()
+	}
+	return result, true
+}
+
+func containsAll(list, values []interface{}) bool {
+	for _, v := range values {
+		if !hasElem(list, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasElem(list []interface{}, val interface{}) bool {
+	for _, item := range list {
+		if c, ok := compare(item, val); ok && c == 0 {
+			return true
+		}
+	}
+	return false
+}
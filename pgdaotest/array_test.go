@@ -0,0 +1,60 @@
+package pgdaotest
+
+import (
+	"reflect"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	pg "github.com/olegfomenko/pg-dao"
+)
+
+type arrayEntry struct {
+	ID     int64    `db:"id"`
+	Name   string   `db:"name"`
+	Tags   []string `db:"tags"`
+	Scores []int32  `db:"scores"`
+}
+
+func TestFakeArrayFilters(t *testing.T) {
+	q := NewDAO(NewStore(), "entries")
+	_, err := q.BulkCreate([]arrayEntry{
+		{Name: "a", Tags: []string{"go", "sql"}, Scores: []int32{1, 2}},
+		{Name: "b", Tags: []string{"go"}, Scores: []int32{3}},
+		{Name: "c"},
+		{Name: "d", Tags: []string{}, Scores: []int32{}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		cond     sq.Sqlizer
+		expected []string
+	}{
+		{name: "contains", cond: pg.ArrayContains("tags", []string{"go"}), expected: []string{"a", "b"}},
+		{name: "contains all", cond: pg.ArrayContains("tags", pq.StringArray{"go", "sql"}), expected: []string{"a"}},
+		{name: "contains empty", cond: pg.ArrayContains("tags", []string{}), expected: []string{"a", "b", "d"}},
+		{name: "contains other type", cond: pg.ArrayContains("scores", []int64{2}), expected: []string{"a"}},
+		{name: "overlaps", cond: pg.ArrayOverlaps("scores", []int{2, 3}), expected: []string{"a", "b"}},
+		{name: "overlaps nothing", cond: pg.ArrayOverlaps("tags", []string{"rust"}), expected: nil},
+		{name: "contained by", cond: pg.ArrayContainedBy("tags", []string{"go", "rust"}), expected: []string{"b", "d"}},
+		{name: "any", cond: pg.Any("name", []string{"a", "c"}), expected: []string{"a", "c"}},
+		{name: "any id", cond: pg.Any("id", []int64{2, 4}), expected: []string{"b", "d"}},
+		{name: "NULL values", cond: pg.ArrayOverlaps("tags", []string(nil)), expected: nil},
+	}
+	for _, c := range cases {
+		var list []arrayEntry
+		if err := q.New().Filter(c.cond).OrderByAsc("name").Select(&list); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var names []string
+		for _, e := range list {
+			names = append(names, e.Name)
+		}
+		if !reflect.DeepEqual(names, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, names)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package pgdaotest
+
+import (
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+// DatabaseURLEnv is the environment variable NewDB takes the PostgreSQL connection URL from.
+const DatabaseURLEnv = "PGDAO_TEST_DATABASE_URL"
+
+// A DBOption configures the database created by NewDB.
+type DBOption func(*dbConfig)
+
+type dbConfig struct {
+	migrations []string
+	// err is reported with t.Fatal by NewDB, options have no access to the test.
+	err error
+}
+
+// WithMigrations runs SQL scripts in the test schema before the test, in order.
+func WithMigrations(scripts ...string) DBOption {
+	return func(c *dbConfig) {
+		c.migrations = append(c.migrations, scripts...)
+	}
+}
+
+// WithMigrationsFS runs *.up.sql files from dir of fsys, ordered by name, e.g.
+// WithMigrationsFS(pg.Migrations, "migrations"). The test fails if they can not be read.
+func WithMigrationsFS(fsys fs.FS, dir string) DBOption {
+	return func(c *dbConfig) {
+		if c.err != nil {
+			return
+		}
+		names, err := fs.Glob(fsys, path.Join(dir, "*.up.sql"))
+		if err != nil {
+			c.err = fmt.Errorf("failed to list migrations: %w", err)
+			return
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			script, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				c.err = fmt.Errorf("failed to read migration %s: %w", name, err)
+				return
+			}
+			c.migrations = append(c.migrations, string(script))
+		}
+	}
+}
+
+// NewDB connects to the database from DatabaseURLEnv and creates a schema isolated for the test,
+// which is dropped on cleanup. The test is skipped if DatabaseURLEnv is not set.
+func NewDB(t testing.TB, opts ...DBOption) *pgdb.DB {
+	t.Helper()
+
+	dbURL := os.Getenv(DatabaseURLEnv)
+	if dbURL == "" {
+		t.Skipf("%s is not set", DatabaseURLEnv)
+	}
+
+	var cfg dbConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.err != nil {
+		t.Fatal(cfg.err)
+	}
+
+	admin, err := pgdb.Open(pgdb.Opts{URL: dbURL, MaxOpenConnections: 1, MaxIdleConnections: 1})
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+
+	schema := fmt.Sprintf("pgdaotest_%d_%d", time.Now().UnixNano(), rand.Int31())
+	if _, err := admin.RawDB().Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.RawDB().Close()
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.RawDB().Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+		admin.RawDB().Close()
+	})
+
+	db, err := pgdb.Open(pgdb.Opts{
+		URL:                withSearchPath(dbURL, schema),
+		MaxOpenConnections: 10,
+		MaxIdleConnections: 10,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect to test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		db.RawDB().Close()
+	})
+
+	// scripts are executed directly with database/sql to avoid placeholders rebinding
+	for i, script := range cfg.migrations {
+		if _, err := db.RawDB().Exec(script); err != nil {
+			t.Fatalf("failed to run migration %d: %v", i, err)
+		}
+	}
+
+	return db
+}
+
+// withSearchPath adds search_path runtime parameter to both URL and key=value connection strings.
+func withSearchPath(dbURL, schema string) string {
+	if !strings.HasPrefix(dbURL, "postgres://") && !strings.HasPrefix(dbURL, "postgresql://") {
+		return dbURL + " search_path=" + schema
+	}
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return dbURL
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,1004 @@
+package pgdaotest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	pg "github.com/olegfomenko/pg-dao"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// ErrUnsupported is returned by methods that require a real database, e.g. ExecRaw or AuditHistory.
+var ErrUnsupported = errors.New("not supported by in-memory DAO")
+
+type predicate func(r row) bool
+
+type order struct {
+	col  string
+	desc bool
+}
+
+type assignment struct {
+	col string
+	val interface{}
+	// fn computes the new value from the current one instead of val if set.
+	fn func(cur interface{}) (interface{}, error)
+	// err fails the update if set, e.g. for expressions that require a real database.
+	err error
+}
+
+// An Option configures the in-memory DAO.
+type Option func(*fake)
+
+// WithVersion sets the version column checked and incremented by Update, see pg.WithVersion.
+func WithVersion(col string) Option {
+	return func(f *fake) {
+		f.versionCol = col
+		f.pgOpts = append(f.pgOpts, pg.WithVersion(col))
+	}
+}
+
+// WithTimestamps sets the columns filled on Create and set on Update, see pg.WithTimestamps.
+func WithTimestamps(createdAt, updatedAt string) Option {
+	return func(f *fake) {
+		f.createdAt = createdAt
+		f.updatedAt = updatedAt
+		f.pgOpts = append(f.pgOpts, pg.WithTimestamps(createdAt, updatedAt))
+	}
+}
+
+// WithClock sets the time source used for timestamps, see pg.WithClock.
+func WithClock(now func() time.Time) Option {
+	return func(f *fake) {
+		f.now = now
+		f.pgOpts = append(f.pgOpts, pg.WithClock(now))
+	}
+}
+
+// WithHooks registers DAO-level hooks, see pg.WithHooks. Hooks implemented by DTOs are run as well.
+func WithHooks(hooks pg.Hooks) Option {
+	return func(f *fake) {
+		f.hooks = append(f.hooks, hooks)
+	}
+}
+
+type fake struct {
+	store      *Store
+	tableName  string
+	opts       []Option
+	versionCol string
+	createdAt  string
+	updatedAt  string
+	now        func() time.Time
+	hooks      []pg.Hooks
+	pgOpts     []pg.Option
+	// stmt is the real DAO every builder call is applied to as well, it builds statements
+	// returned by ToSQL and by writes in dry-run mode.
+	stmt pg.DAO
+
+	count     bool
+	dryRun    bool
+	filters   []predicate
+	orders    []order
+	limit     *uint64
+	offset    uint64
+	updWhere  []predicate
+	updSet    []assignment
+	version   *int64
+	dltWhere  []predicate
+	fullTable bool
+	err       error
+}
+
+// NewDAO returns an in-memory pg.DAO for the table stored in store. DTOs are written and read
+// with `db` tags like the real DAO does; Create assigns sequential ids unless the DTO sets one.
+// Timestamps, versions and hooks are managed as configured by options. ToSQL and writes
+// in dry-run mode return statements built by the real DAO.
+// Transactions are implemented with snapshots of the whole store, so concurrent writes
+// made while a transaction is running are lost on its rollback.
+func NewDAO(store *Store, tableName string, opts ...Option) pg.DAO {
+	f := &fake{
+		store:     store,
+		tableName: tableName,
+		opts:      opts,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	f.stmt = pg.NewDAO(nil, tableName, append(f.pgOpts, pg.WithExecutor(noExecutor{}))...)
+	return f
+}
+
+func (f *fake) Clone() pg.DAO {
+	return NewDAO(f.store, f.tableName, f.opts...)
+}
+
+func (f *fake) New() pg.DAO {
+	n := NewDAO(f.store, f.tableName, f.opts...).(*fake)
+	n.dryRun = f.dryRun
+	return n
+}
+
+func (f *fake) Count() pg.DAO {
+	c := f.New().(*fake)
+	c.count = true
+	c.stmt = c.stmt.Count()
+	return c
+}
+
+func (f *fake) Create(dto interface{}) (int64, error) {
+	return f.CreateCtx(context.TODO(), dto)
+}
+
+func (f *fake) CreateCtx(ctx context.Context, dto interface{}) (int64, error) {
+	if f.dryRun {
+		return f.stmt.DryRun().CreateCtx(ctx, dto)
+	}
+	if err := f.beforeCreate(ctx, dto); err != nil {
+		return 0, err
+	}
+
+	r, err := f.createValues(dto)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := f.insert(r)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], f.afterCreate(ctx, ids[0], dto)
+}
+
+func (f *fake) BulkCreate(dtos interface{}) ([]int64, error) {
+	return f.BulkCreateCtx(context.TODO(), dtos)
+}
+
+func (f *fake) BulkCreateCtx(ctx context.Context, dtos interface{}) ([]int64, error) {
+	list := reflect.ValueOf(dtos)
+	if list.Kind() != reflect.Slice {
+		return nil, errors.New("argument is not a slice")
+	}
+	if list.Len() == 0 {
+		return nil, nil
+	}
+	if f.dryRun {
+		return f.stmt.DryRun().BulkCreateCtx(ctx, dtos)
+	}
+
+	rows := make([]row, list.Len())
+	for i := range rows {
+		dto := list.Index(i).Interface()
+		if err := f.beforeCreate(ctx, dto); err != nil {
+			return nil, err
+		}
+		r, err := f.createValues(dto)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = r
+	}
+
+	ids, err := f.insert(rows...)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		if err := f.afterCreate(ctx, id, list.Index(i).Interface()); err != nil {
+			return ids, err
+		}
+	}
+	return ids, nil
+}
+
+// createValues returns the row Create writes for dto with managed columns set.
+func (f *fake) createValues(dto interface{}) (row, error) {
+	r, err := pg.ColumnValues(dto)
+	if err != nil {
+		return nil, err
+	}
+	if f.createdAt != "" && isZero(r[f.createdAt]) {
+		r[f.createdAt] = f.now()
+	}
+	if f.versionCol != "" && isZero(r[f.versionCol]) {
+		r[f.versionCol] = int64(1)
+	}
+	return r, nil
+}
+
+// insert stores rows and returns their ids. Rows without id get the next id of the table, explicit ids
+// are kept and do not move it, like a serial column does. A duplicate id fails with a unique violation
+// and no rows are stored.
+func (f *fake) insert(rows ...row) ([]int64, error) {
+	f.store.mu.Lock()
+	defer f.store.mu.Unlock()
+
+	t := f.store.table(f.tableName)
+	taken := make(map[int64]bool, len(t.rows)+len(rows))
+	for _, r := range t.rows {
+		if id, ok := toInt(normalize(r[pg.IdColumn])); ok {
+			taken[id] = true
+		}
+	}
+
+	ids := make([]int64, len(rows))
+	next := t.nextID
+	for i, r := range rows {
+		id, ok := toInt(normalize(r[pg.IdColumn]))
+		if !ok {
+			id = next
+			next++
+		}
+		if taken[id] {
+			return nil, &pq.Error{
+				Code:       "23505",
+				Message:    fmt.Sprintf("duplicate key value violates unique constraint \"%s_pkey\"", f.tableName),
+				Table:      f.tableName,
+				Constraint: f.tableName + "_pkey",
+			}
+		}
+		taken[id] = true
+		ids[i] = id
+	}
+
+	t.nextID = next
+	for i, r := range rows {
+		r[pg.IdColumn] = ids[i]
+		t.rows = append(t.rows, r)
+	}
+	return ids, nil
+}
+
+func (f *fake) FilterByID(id int64) pg.DAO {
+	f.stmt.FilterByID(id)
+	f.filters = append(f.filters, eqPredicate(pg.IdColumn, id))
+	return f
+}
+
+func (f *fake) FilterOnlyAfter(time time.Time) pg.DAO {
+	f.stmt.FilterOnlyAfter(time)
+	f.filters = append(f.filters, cmpPredicate(f.createdAtColumn(), time, func(c int) bool { return c > 0 }))
+	return f
+}
+
+func (f *fake) FilterOnlyBefore(time time.Time) pg.DAO {
+	f.stmt.FilterOnlyBefore(time)
+	f.filters = append(f.filters, cmpPredicate(f.createdAtColumn(), time, func(c int) bool { return c < 0 }))
+	return f
+}
+
+func (f *fake) createdAtColumn() string {
+	if f.createdAt == "" {
+		return pg.CreatedAtColumn
+	}
+	return f.createdAt
+}
+
+func (f *fake) FilterGreater(col string, val interface{}) pg.DAO {
+	f.stmt.FilterGreater(col, val)
+	f.filters = append(f.filters, cmpPredicate(col, val, func(c int) bool { return c > 0 }))
+	return f
+}
+
+func (f *fake) FilterLess(col string, val interface{}) pg.DAO {
+	f.stmt.FilterLess(col, val)
+	f.filters = append(f.filters, cmpPredicate(col, val, func(c int) bool { return c < 0 }))
+	return f
+}
+
+func (f *fake) FilterByColumn(col string, val interface{}) pg.DAO {
+	f.stmt.FilterByColumn(col, val)
+	f.filters = append(f.filters, eqPredicate(col, val))
+	return f
+}
+
+// Filter, UpdateWhere and DeleteWhere support squirrel Eq, NotEq, Lt, LtOrEq, Gt, GtOrEq, And and Or
+// conditions, keyset conditions, array filters and JSON filters except JSONPathExists. Other conditions,
+// e.g. expressions, make statements of the DAO fail with ErrUnsupported.
+func (f *fake) Filter(cond sq.Sqlizer) pg.DAO {
+	f.stmt.Filter(cond)
+	f.filters = f.condWhere(f.filters, cond)
+	return f
+}
+
+// condWhere appends the predicate of cond to where, unsupported conditions are stored as the DAO error.
+func (f *fake) condWhere(where []predicate, cond sq.Sqlizer) []predicate {
+	p, ok := condPredicate(cond)
+	if !ok {
+		if f.err == nil {
+			f.err = errors.Wrap(ErrUnsupported, "unsupported filter", map[string]interface{}{
+				"type": fmt.Sprintf("%T", cond),
+			})
+		}
+		return where
+	}
+	return append(where, p)
+}
+
+// Search requires a real database, statements of the DAO fail with ErrUnsupported.
+func (f *fake) Search(cols []string, query string, opts pg.SearchOptions) pg.DAO {
+	f.stmt.Search(cols, query, opts)
+	if f.err == nil {
+		f.err = errors.Wrap(ErrUnsupported, "full-text search is not supported")
+	}
+	return f
+}
+
+func (f *fake) Get(dto interface{}) (bool, error) {
+	return f.GetCtx(context.TODO(), dto)
+}
+
+func (f *fake) GetCtx(ctx context.Context, dto interface{}) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	dest := reflect.ValueOf(dto)
+	if dest.Kind() != reflect.Ptr {
+		return false, errors.New("argument is not a pointer")
+	}
+
+	rows := f.selectRows()
+	if f.count {
+		return true, assign(dest.Elem(), int64(len(rows)))
+	}
+	if len(rows) == 0 {
+		return false, nil
+	}
+	if err := scanRow(dest.Elem(), rows[0]); err != nil {
+		return true, err
+	}
+	return true, f.afterFind(ctx, dto)
+}
+
+func (f *fake) Select(list interface{}) error {
+	return f.SelectCtx(context.TODO(), list)
+}
+
+func (f *fake) SelectCtx(ctx context.Context, list interface{}) error {
+	if f.err != nil {
+		return f.err
+	}
+	dest := reflect.ValueOf(list)
+	if dest.Kind() != reflect.Ptr || dest.Elem().Kind() != reflect.Slice {
+		return errors.New("argument is not a slice pointer")
+	}
+
+	slice := dest.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	result := reflect.MakeSlice(slice.Type(), 0, 0)
+	for _, r := range f.selectRows() {
+		elem := reflect.New(elemType)
+		if err := scanRow(elem.Elem(), r); err != nil {
+			return err
+		}
+		if !isPtr {
+			elem = elem.Elem()
+		}
+		result = reflect.Append(result, elem)
+	}
+	slice.Set(result)
+	return f.afterFindAll(ctx, slice)
+}
+
+func (f *fake) Limit(limit uint64) pg.DAO {
+	f.stmt.Limit(limit)
+	f.limit = &limit
+	return f
+}
+
+func (f *fake) OrderByDesc(col string) pg.DAO {
+	f.stmt.OrderByDesc(col)
+	f.orders = append(f.orders, order{col: col, desc: true})
+	return f
+}
+
+func (f *fake) OrderByAsc(col string) pg.DAO {
+	f.stmt.OrderByAsc(col)
+	f.orders = append(f.orders, order{col: col})
+	return f
+}
+
+func (f *fake) UpdateWhereID(id int64) pg.DAO {
+	f.stmt.UpdateWhereID(id)
+	f.updWhere = append(f.updWhere, eqPredicate(pg.IdColumn, id))
+	return f
+}
+
+func (f *fake) UpdateWhereVersion(version int64) pg.DAO {
+	f.stmt.UpdateWhereVersion(version)
+	f.version = &version
+	return f
+}
+
+// UpdateColumn sets col to val, expressions passed as val make Update fail with ErrUnsupported like UpdateExpr.
+func (f *fake) UpdateColumn(col string, val interface{}) pg.DAO {
+	f.stmt.UpdateColumn(col, val)
+	if _, ok := val.(sq.Sqlizer); ok {
+		f.updSet = append(f.updSet, exprAssignment(col))
+		return f
+	}
+	f.updSet = append(f.updSet, assignment{col: col, val: val})
+	return f
+}
+
+// UpdateDTO sets written columns of dto except id and columns managed by the DAO, like the real DAO does.
+func (f *fake) UpdateDTO(dto interface{}) pg.DAO {
+	f.stmt.UpdateDTO(dto)
+	values, err := pg.ColumnValues(dto)
+	if err != nil {
+		if f.err == nil {
+			f.err = err
+		}
+		return f
+	}
+
+	if f.versionCol != "" {
+		if val, ok := values[f.versionCol]; ok {
+			v, ok := toInt(normalize(val))
+			if !ok && f.err == nil {
+				f.err = errors.From(errors.New("invalid version"), map[string]interface{}{"column": f.versionCol})
+			}
+			f.version = &v
+		}
+	}
+	for _, col := range []string{pg.IdColumn, f.createdAt, f.updatedAt, f.versionCol} {
+		delete(values, col)
+	}
+
+	cols := make([]string, 0, len(values))
+	for col := range values {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for _, col := range cols {
+		f.updSet = append(f.updSet, assignment{col: col, val: values[col]})
+	}
+	return f
+}
+
+// UpdateExpr requires a real database, Update fails with ErrUnsupported while selects of the DAO still work.
+func (f *fake) UpdateExpr(col string, expr sq.Sqlizer) pg.DAO {
+	f.stmt.UpdateExpr(col, expr)
+	f.updSet = append(f.updSet, exprAssignment(col))
+	return f
+}
+
+func exprAssignment(col string) assignment {
+	return assignment{col: col, err: errors.Wrap(ErrUnsupported, "update expressions are not supported",
+		map[string]interface{}{"column": col})}
+}
+
+func (f *fake) Increment(col string, n interface{}) pg.DAO {
+	f.stmt.Increment(col, n)
+	f.updSet = append(f.updSet, assignment{col: col, fn: func(cur interface{}) (interface{}, error) {
+		return add(cur, n, 1)
+	}})
+	return f
+}
+
+func (f *fake) Decrement(col string, n interface{}) pg.DAO {
+	f.stmt.Decrement(col, n)
+	f.updSet = append(f.updSet, assignment{col: col, fn: func(cur interface{}) (interface{}, error) {
+		return add(cur, n, -1)
+	}})
+	return f
+}
+
+func (f *fake) ArrayAppend(col string, val interface{}) pg.DAO {
+	f.stmt.ArrayAppend(col, val)
+	f.updSet = append(f.updSet, assignment{col: col, fn: func(cur interface{}) (interface{}, error) {
+		list, err := arrayOf(cur, val)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.Append(list, reflect.ValueOf(val).Convert(list.Type().Elem())).Interface(), nil
+	}})
+	return f
+}
+
+func (f *fake) ArrayRemove(col string, val interface{}) pg.DAO {
+	f.stmt.ArrayRemove(col, val)
+	f.updSet = append(f.updSet, assignment{col: col, fn: func(cur interface{}) (interface{}, error) {
+		list, err := arrayOf(cur, val)
+		if err != nil {
+			return nil, err
+		}
+		if list.IsNil() {
+			return cur, nil
+		}
+		result := reflect.MakeSlice(list.Type(), 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			if c, ok := compare(list.Index(i).Interface(), val); !ok || c != 0 {
+				result = reflect.Append(result, list.Index(i))
+			}
+		}
+		return result.Interface(), nil
+	}})
+	return f
+}
+
+func (f *fake) Update() error {
+	return f.UpdateCtx(context.TODO())
+}
+
+func (f *fake) UpdateCtx(ctx context.Context) error {
+	_, err := f.update(ctx, false)
+	return err
+}
+
+func (f *fake) BulkUpdate() (int64, error) {
+	return f.BulkUpdateCtx(context.TODO())
+}
+
+func (f *fake) BulkUpdateCtx(ctx context.Context) (int64, error) {
+	return f.update(ctx, true)
+}
+
+func (f *fake) update(ctx context.Context, bulk bool) (int64, error) {
+	if f.dryRun {
+		if bulk {
+			return f.stmt.DryRun().BulkUpdateCtx(ctx)
+		}
+		return 0, f.stmt.DryRun().UpdateCtx(ctx)
+	}
+	err := f.runQueryHooks(ctx, func(h pg.Hooks) func(context.Context, pg.DAO) error {
+		return h.BeforeUpdate
+	})
+	if err != nil {
+		return 0, err
+	}
+	if f.err != nil {
+		return 0, f.err
+	}
+	for _, a := range f.updSet {
+		if a.err != nil {
+			return 0, a.err
+		}
+	}
+	if len(f.updWhere) == 0 && !f.fullTable {
+		return 0, pg.ErrUnfiltered
+	}
+
+	where := f.updWhere
+	if f.versionCol != "" {
+		if f.version != nil {
+			where = append(where, eqPredicate(f.versionCol, *f.version))
+		} else if !bulk {
+			return 0, errors.New("version is required to update versioned record")
+		}
+	}
+
+	updated, err := f.updateRows(where)
+	if err != nil {
+		return 0, err
+	}
+	if updated == 0 && !bulk {
+		if f.versionCol != "" {
+			return 0, pg.ErrStaleVersion
+		}
+		return 0, pg.ErrNotFound
+	}
+
+	return updated, f.runQueryHooks(ctx, func(h pg.Hooks) func(context.Context, pg.DAO) error {
+		return h.AfterUpdate
+	})
+}
+
+// updateRows applies assignments to copies of rows matching where and replaces the rows
+// only if all of them succeed, so a failed update leaves the table untouched.
+func (f *fake) updateRows(where []predicate) (int64, error) {
+	setUpdatedAt := f.updatedAt != ""
+	for _, a := range f.updSet {
+		setUpdatedAt = setUpdatedAt && a.col != f.updatedAt
+	}
+
+	f.store.mu.Lock()
+	defer f.store.mu.Unlock()
+
+	rows := f.store.table(f.tableName).rows
+	updates := make(map[int]row)
+	for i, r := range rows {
+		if !matches(r, where) {
+			continue
+		}
+
+		next := make(row, len(r))
+		for col, val := range r {
+			next[col] = val
+		}
+		for _, a := range f.updSet {
+			if a.fn == nil {
+				next[a.col] = a.val
+				continue
+			}
+			val, err := a.fn(next[a.col])
+			if err != nil {
+				return 0, errors.Wrap(err, "failed to update column", map[string]interface{}{"column": a.col})
+			}
+			next[a.col] = val
+		}
+		if setUpdatedAt {
+			next[f.updatedAt] = f.now()
+		}
+		if f.versionCol != "" {
+			version, err := add(r[f.versionCol], 1, 1)
+			if err != nil {
+				return 0, errors.Wrap(err, "failed to increment version")
+			}
+			next[f.versionCol] = version
+		}
+		updates[i] = next
+	}
+
+	for i, next := range updates {
+		rows[i] = next
+	}
+	return int64(len(updates)), nil
+}
+
+func (f *fake) UpdateWhere(cond sq.Sqlizer) pg.DAO {
+	f.stmt.UpdateWhere(cond)
+	f.updWhere = f.condWhere(f.updWhere, cond)
+	return f
+}
+
+func (f *fake) DeleteWhereVal(col string, val interface{}) pg.DAO {
+	f.stmt.DeleteWhereVal(col, val)
+	f.dltWhere = append(f.dltWhere, eqPredicate(col, val))
+	return f
+}
+
+func (f *fake) DeleteWhereID(id int64) pg.DAO {
+	f.stmt.DeleteWhereID(id)
+	f.dltWhere = append(f.dltWhere, eqPredicate(pg.IdColumn, id))
+	return f
+}
+
+func (f *fake) DeleteWhere(cond sq.Sqlizer) pg.DAO {
+	f.stmt.DeleteWhere(cond)
+	f.dltWhere = f.condWhere(f.dltWhere, cond)
+	return f
+}
+
+func (f *fake) AllowFullTable() pg.DAO {
+	f.stmt.AllowFullTable()
+	f.fullTable = true
+	return f
+}
+
+func (f *fake) Delete() error {
+	return f.DeleteCtx(context.TODO())
+}
+
+func (f *fake) DeleteCtx(ctx context.Context) error {
+	_, err := f.BulkDeleteCtx(ctx)
+	return err
+}
+
+func (f *fake) BulkDelete() (int64, error) {
+	return f.BulkDeleteCtx(context.TODO())
+}
+
+func (f *fake) BulkDeleteCtx(ctx context.Context) (int64, error) {
+	if f.dryRun {
+		return f.stmt.DryRun().BulkDeleteCtx(ctx)
+	}
+	err := f.runQueryHooks(ctx, func(h pg.Hooks) func(context.Context, pg.DAO) error {
+		return h.BeforeDelete
+	})
+	if err != nil {
+		return 0, err
+	}
+	if f.err != nil {
+		return 0, f.err
+	}
+	if len(f.dltWhere) == 0 && !f.fullTable {
+		return 0, pg.ErrUnfiltered
+	}
+
+	f.store.mu.Lock()
+	t := f.store.table(f.tableName)
+	kept := t.rows[:0]
+	for _, r := range t.rows {
+		if !matches(r, f.dltWhere) {
+			kept = append(kept, r)
+		}
+	}
+	deleted := int64(len(t.rows) - len(kept))
+	t.rows = kept
+	f.store.mu.Unlock()
+
+	return deleted, f.runQueryHooks(ctx, func(h pg.Hooks) func(context.Context, pg.DAO) error {
+		return h.AfterDelete
+	})
+}
+
+func (f *fake) AuditHistory(context.Context, int64) ([]pg.AuditEntry, error) {
+	return nil, ErrUnsupported
+}
+
+func (f *fake) Page(params pgdb.OffsetPageParams, column string) pg.DAO {
+	f.stmt.Page(params, column)
+	if params.Limit == 0 {
+		params.Limit = 15
+	}
+	if params.Order == "" {
+		params.Order = pgdb.OrderTypeDesc
+	}
+
+	f.limit = &params.Limit
+	f.offset = params.Limit * params.PageNumber
+	f.orderBy(params.Order, column)
+	return f
+}
+
+func (f *fake) Cursor(params pgdb.CursorPageParams, column string) pg.DAO {
+	f.stmt.Cursor(params, column)
+	if params.Limit == 0 {
+		params.Limit = 15
+	}
+	if params.Order == "" {
+		params.Order = pgdb.OrderTypeDesc
+	}
+
+	f.limit = &params.Limit
+	if params.Cursor != 0 {
+		if params.Order == pgdb.OrderTypeAsc {
+			f.filters = append(f.filters, cmpPredicate(column, params.Cursor, func(c int) bool { return c > 0 }))
+		} else {
+			f.filters = append(f.filters, cmpPredicate(column, params.Cursor, func(c int) bool { return c < 0 }))
+		}
+	}
+	f.orderBy(params.Order, column)
+	return f
+}
+
+// orderBy mirrors pgdb page params behaviour, an unexpected order is returned as an error on execution.
+func (f *fake) orderBy(orderType, column string) {
+	switch orderType {
+	case pgdb.OrderTypeAsc:
+		f.orders = append(f.orders, order{col: column})
+	case pgdb.OrderTypeDesc:
+		f.orders = append(f.orders, order{col: column, desc: true})
+	default:
+		if f.err == nil {
+			f.err = errors.From(errors.New("unexpected order type"), map[string]interface{}{"order": orderType})
+		}
+	}
+}
+
+func (f *fake) Transaction(fn func(q pg.DAO) error) error {
+	snapshot := f.store.snapshot()
+	if err := fn(f); err != nil {
+		f.store.restore(snapshot)
+		return errors.Wrap(err, "failed to execute statements")
+	}
+	return nil
+}
+
+func (f *fake) TransactionSerializable(fn func(q pg.DAO) error) error {
+	return f.Transaction(fn)
+}
+
+func (f *fake) TransactionWithLevel(_ sql.IsolationLevel, fn func(q pg.DAO) error) error {
+	return f.Transaction(fn)
+}
+
+func (f *fake) Validate(context.Context, interface{}) error {
+	return ErrUnsupported
+}
+
+// ToSQL returns the statement the real DAO would execute.
+func (f *fake) ToSQL() (string, []interface{}, error) {
+	return f.stmt.ToSQL()
+}
+
+func (f *fake) DryRun() pg.DAO {
+	f.dryRun = true
+	return f
+}
+
+func (f *fake) ExecRaw(func(raw *pgdb.DB) error) error {
+	return ErrUnsupported
+}
+
+func (f *fake) ExecRawCtx(context.Context, func(ctx context.Context, raw *pgdb.DB) error) error {
+	return ErrUnsupported
+}
+
+// selectRows returns copies of rows matching filters with order, offset and limit applied.
+func (f *fake) selectRows() []row {
+	f.store.mu.Lock()
+	var rows []row
+	for _, r := range f.store.table(f.tableName).rows {
+		if matches(r, f.filters) {
+			c := make(row, len(r))
+			for col, val := range r {
+				c[col] = val
+			}
+			rows = append(rows, c)
+		}
+	}
+	f.store.mu.Unlock()
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, o := range f.orders {
+			c, ok := compare(rows[i][o.col], rows[j][o.col])
+			if !ok || c == 0 {
+				continue
+			}
+			return (c < 0) != o.desc
+		}
+		return false
+	})
+
+	if f.offset >= uint64(len(rows)) {
+		rows = nil
+	} else {
+		rows = rows[f.offset:]
+	}
+	if f.limit != nil && *f.limit < uint64(len(rows)) {
+		rows = rows[:*f.limit]
+	}
+	return rows
+}
+
+func matches(r row, predicates []predicate) bool {
+	for _, p := range predicates {
+		if !p(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// eqPredicate mirrors squirrel.Eq: nil matches NULL and slices match any of their elements.
+func eqPredicate(col string, val interface{}) predicate {
+	v := reflect.ValueOf(val)
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		return func(r row) bool {
+			for i := 0; i < v.Len(); i++ {
+				if c, ok := compare(r[col], v.Index(i).Interface()); ok && c == 0 {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	if normalize(val) == nil {
+		return func(r row) bool {
+			return normalize(r[col]) == nil
+		}
+	}
+	return cmpPredicate(col, val, func(c int) bool { return c == 0 })
+}
+
+func cmpPredicate(col string, val interface{}, fn func(c int) bool) predicate {
+	return func(r row) bool {
+		c, ok := compare(r[col], val)
+		return ok && fn(c)
+	}
+}
+
+// condPredicate evaluates squirrel conditions the way Postgres does, it reports false for unsupported ones.
+func condPredicate(cond sq.Sqlizer) (predicate, bool) {
+	switch cond := cond.(type) {
+	case sq.Eq:
+		return mapPredicate(cond, eqPredicate)
+	case sq.NotEq:
+		return mapPredicate(cond, func(col string, val interface{}) predicate {
+			eq := eqPredicate(col, val)
+			return func(r row) bool {
+				// NULL never differs from a non-NULL value in SQL.
+				return !eq(r) && (normalize(val) == nil || normalize(r[col]) != nil)
+			}
+		})
+	case sq.Lt:
+		return mapPredicate(cond, cmpFunc(func(c int) bool { return c < 0 }))
+	case sq.LtOrEq:
+		return mapPredicate(cond, cmpFunc(func(c int) bool { return c <= 0 }))
+	case sq.Gt:
+		return mapPredicate(cond, cmpFunc(func(c int) bool { return c > 0 }))
+	case sq.GtOrEq:
+		return mapPredicate(cond, cmpFunc(func(c int) bool { return c >= 0 }))
+	case sq.And:
+		return listPredicate(cond, true)
+	case sq.Or:
+		return listPredicate(cond, false)
+	case pg.KeysetCondition:
+		return keysetPredicate(cond)
+	case pg.JSONCondition:
+		return jsonPredicate(cond)
+	case pg.ArrayCondition:
+		return arrayPredicate(cond)
+	}
+	return nil, false
+}
+
+func cmpFunc(fn func(c int) bool) func(col string, val interface{}) predicate {
+	return func(col string, val interface{}) predicate {
+		return cmpPredicate(col, val, fn)
+	}
+}
+
+func mapPredicate(cond map[string]interface{}, fn func(col string, val interface{}) predicate) (predicate, bool) {
+	predicates := make([]predicate, 0, len(cond))
+	for col, val := range cond {
+		predicates = append(predicates, fn(col, val))
+	}
+	return func(r row) bool {
+		return matches(r, predicates)
+	}, true
+}
+
+func listPredicate(conds []sq.Sqlizer, all bool) (predicate, bool) {
+	predicates := make([]predicate, 0, len(conds))
+	for _, cond := range conds {
+		p, ok := condPredicate(cond)
+		if !ok {
+			return nil, false
+		}
+		predicates = append(predicates, p)
+	}
+	return func(r row) bool {
+		if all {
+			return matches(r, predicates)
+		}
+		for _, p := range predicates {
+			if p(r) {
+				return true
+			}
+		}
+		return false
+	}, true
+}
+
+// keysetPredicate matches rows following the key in the order of columns.
+func keysetPredicate(cond pg.KeysetCondition) (predicate, bool) {
+	if len(cond.Columns) != len(cond.Values) {
+		return nil, false
+	}
+	return func(r row) bool {
+		for i, col := range cond.Columns {
+			c, ok := compare(r[col.Name], cond.Values[i])
+			if !ok {
+				return false
+			}
+			if c != 0 {
+				return (c > 0) != col.Desc
+			}
+		}
+		return false
+	}, true
+}
+
+// noExecutor is the executor of the statement builder, which never executes statements.
+type noExecutor struct{}
+
+func (noExecutor) GetContext(context.Context, interface{}, string, ...interface{}) error {
+	return ErrUnsupported
+}
+
+func (noExecutor) SelectContext(context.Context, interface{}, string, ...interface{}) error {
+	return ErrUnsupported
+}
+
+func (noExecutor) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, ErrUnsupported
+}
+
+func (noExecutor) TransactionWithOptions(*sql.TxOptions, func() error) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,260 @@
+package pgdaotest
+
+import (
+	"context"
+	goerr "errors"
+	"reflect"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	pg "github.com/olegfomenko/pg-dao"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type fakeEntry struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	Tags      []string  `db:"tags"`
+	Version   int64     `db:"version"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+var fakeEpoch = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// newPair returns the in-memory DAO and the real DAO configured the same way.
+func newPair() (pg.DAO, pg.DAO) {
+	now := func() time.Time { return fakeEpoch }
+	f := NewDAO(NewStore(), "entries",
+		WithTimestamps(pg.CreatedAtColumn, pg.UpdatedAtColumn), WithVersion("version"), WithClock(now))
+	real := NewRecorder().DAO("entries",
+		pg.WithTimestamps(pg.CreatedAtColumn, pg.UpdatedAtColumn), pg.WithVersion("version"), pg.WithClock(now))
+	return f, real
+}
+
+func dryRunStatement(t *testing.T, name string, err error) *pg.DryRunStatement {
+	t.Helper()
+
+	var stmt *pg.DryRunStatement
+	if !goerr.As(err, &stmt) {
+		t.Fatalf("%s: expected dry run statement, got %v", name, err)
+	}
+	return stmt
+}
+
+func TestFakeMatchesRealStatements(t *testing.T) {
+	entry := fakeEntry{ID: 3, Name: "a", Tags: []string{"x"}, Version: 2, CreatedAt: fakeEpoch}
+	cases := []struct {
+		name string
+		run  func(q pg.DAO) error
+	}{
+		{
+			name: "create",
+			run: func(q pg.DAO) error {
+				_, err := q.DryRun().Create(fakeEntry{Name: "a", Tags: []string{"x"}})
+				return err
+			},
+		},
+		{
+			name: "bulk create",
+			run: func(q pg.DAO) error {
+				_, err := q.DryRun().BulkCreate([]fakeEntry{{Name: "a"}, {ID: 9, Name: "b"}})
+				return err
+			},
+		},
+		{
+			name: "update dto",
+			run: func(q pg.DAO) error {
+				return q.DryRun().UpdateWhereID(entry.ID).UpdateDTO(entry).Update()
+			},
+		},
+		{
+			name: "bulk update",
+			run: func(q pg.DAO) error {
+				_, err := q.DryRun().UpdateWhere(pg.JSONHasKey("meta", "k")).Increment("score", 1).BulkUpdate()
+				return err
+			},
+		},
+		{
+			name: "delete",
+			run: func(q pg.DAO) error {
+				return q.DryRun().DeleteWhereID(entry.ID).Delete()
+			},
+		},
+	}
+
+	for _, c := range cases {
+		f, real := newPair()
+		expected := dryRunStatement(t, c.name, c.run(real))
+		got := dryRunStatement(t, c.name, c.run(f))
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("%s: expected %+v, got %+v", c.name, expected, got)
+		}
+	}
+
+	f, real := newPair()
+	page := pgdb.CursorPageParams{Cursor: 5, Limit: 2, Order: pgdb.OrderTypeAsc}
+	expectedSQL, expectedArgs, err := real.FilterOnlyAfter(fakeEpoch).Cursor(page, "id").ToSQL()
+	if err != nil {
+		t.Fatalf("to sql: %v", err)
+	}
+	sql, args, err := f.FilterOnlyAfter(fakeEpoch).Cursor(page, "id").ToSQL()
+	if err != nil {
+		t.Fatalf("to sql: %v", err)
+	}
+	if sql != expectedSQL || !reflect.DeepEqual(args, expectedArgs) {
+		t.Fatalf("to sql: expected %s %v, got %s %v", expectedSQL, expectedArgs, sql, args)
+	}
+}
+
+func TestFakeUpdateDTOKeepsManagedColumns(t *testing.T) {
+	f, _ := newPair()
+	ctx := context.Background()
+
+	id, err := f.CreateCtx(ctx, fakeEntry{Name: "a"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	// the DTO has zero timestamps, as if it was built from a request
+	err = f.New().UpdateWhereID(id).UpdateDTO(fakeEntry{Name: "b", Version: 1}).UpdateCtx(ctx)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	var got fakeEntry
+	if ok, err := f.New().FilterByID(id).GetCtx(ctx, &got); err != nil || !ok {
+		t.Fatalf("get: %v, %v", ok, err)
+	}
+	expected := fakeEntry{ID: id, Name: "b", Version: 2, CreatedAt: fakeEpoch, UpdatedAt: fakeEpoch}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestFakeCreateIDs(t *testing.T) {
+	f, _ := newPair()
+
+	cases := []struct {
+		name     string
+		dto      fakeEntry
+		expected int64
+		fails    bool
+	}{
+		{name: "explicit", dto: fakeEntry{ID: 10}, expected: 10},
+		{name: "sequence", dto: fakeEntry{}, expected: 1},
+		{name: "duplicate", dto: fakeEntry{ID: 10}, fails: true},
+		{name: "sequence after duplicate", dto: fakeEntry{}, expected: 2},
+	}
+	for _, c := range cases {
+		id, err := f.New().Create(c.dto)
+		if c.fails {
+			if pg.ClassifyError(err) != pg.ErrorClassConstraint {
+				t.Fatalf("%s: expected constraint error, got %v", c.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if id != c.expected {
+			t.Fatalf("%s: expected id %d, got %d", c.name, c.expected, id)
+		}
+	}
+
+	ids, err := f.New().BulkCreate([]fakeEntry{{Name: "x"}, {ID: 20, Name: "y"}})
+	if err != nil {
+		t.Fatalf("bulk create: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []int64{3, 20}) {
+		t.Fatalf("bulk create: expected [3 20], got %v", ids)
+	}
+}
+
+type counterEntry struct {
+	ID     int64    `db:"id"`
+	Score  int64    `db:"score"`
+	Tags   []string `db:"tags"`
+	Scores []int32  `db:"scores"`
+}
+
+func TestFakeUpdateExpressions(t *testing.T) {
+	initial := counterEntry{Score: 1, Tags: []string{"x"}, Scores: []int32{1}}
+	cases := []struct {
+		name        string
+		update      func(q pg.DAO) pg.DAO
+		expected    counterEntry
+		fails       bool
+		unsupported bool
+	}{
+		{
+			name:     "append and remove",
+			update:   func(q pg.DAO) pg.DAO { return q.ArrayAppend("tags", "go").ArrayRemove("tags", "x") },
+			expected: counterEntry{Score: 1, Tags: []string{"go"}, Scores: []int32{1}},
+		},
+		{
+			name:     "append integer of other size",
+			update:   func(q pg.DAO) pg.DAO { return q.ArrayAppend("scores", int64(2)).Increment("score", 2) },
+			expected: counterEntry{Score: 3, Tags: []string{"x"}, Scores: []int32{1, 2}},
+		},
+		{
+			name:   "append integer to text array",
+			update: func(q pg.DAO) pg.DAO { return q.ArrayAppend("tags", 1) },
+			fails:  true,
+		},
+		{
+			name:   "append float to integer array",
+			update: func(q pg.DAO) pg.DAO { return q.ArrayAppend("scores", 1.5) },
+			fails:  true,
+		},
+		{
+			name:        "append NULL",
+			update:      func(q pg.DAO) pg.DAO { return q.ArrayAppend("tags", nil) },
+			unsupported: true,
+		},
+		{
+			name:        "expression",
+			update:      func(q pg.DAO) pg.DAO { return q.Increment("score", 1).UpdateExpr("score", sq.Expr("score * 2")) },
+			unsupported: true,
+		},
+		{
+			name:        "expression value",
+			update:      func(q pg.DAO) pg.DAO { return q.UpdateColumn("score", sq.Expr("score * 2")) },
+			unsupported: true,
+		},
+	}
+
+	for _, c := range cases {
+		q := NewDAO(NewStore(), "entries")
+		id, err := q.Create(initial)
+		if err != nil {
+			t.Fatalf("%s: create: %v", c.name, err)
+		}
+
+		q = c.update(q.New().FilterByID(id).UpdateWhereID(id))
+		err = q.Update()
+		switch {
+		case c.unsupported && errors.Cause(err) != ErrUnsupported:
+			t.Fatalf("%s: expected ErrUnsupported, got %v", c.name, err)
+		case c.fails && (err == nil || errors.Cause(err) == ErrUnsupported):
+			t.Fatalf("%s: expected type error, got %v", c.name, err)
+		case !c.fails && !c.unsupported && err != nil:
+			t.Fatalf("%s: update: %v", c.name, err)
+		}
+
+		expected := c.expected
+		if c.fails || c.unsupported {
+			expected = initial
+		}
+		expected.ID = id
+		// a failed update leaves the row untouched and does not affect selects of the DAO
+		var got counterEntry
+		if ok, err := q.Get(&got); err != nil || !ok {
+			t.Fatalf("%s: get: %v, %v", c.name, ok, err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("%s: expected %+v, got %+v", c.name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package pgdaotest
+
+import (
+	"context"
+	"reflect"
+
+	pg "github.com/olegfomenko/pg-dao"
+)
+
+// Hooks are run the same way the real DAO runs them, except in dry-run mode.
+
+func (f *fake) beforeCreate(ctx context.Context, dto interface{}) error {
+	if h, ok := dto.(pg.BeforeCreator); ok {
+		if err := h.BeforeCreate(ctx); err != nil {
+			return err
+		}
+	}
+	for _, h := range f.hooks {
+		if h.BeforeCreate == nil {
+			continue
+		}
+		if err := h.BeforeCreate(ctx, dto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fake) afterCreate(ctx context.Context, id int64, dto interface{}) error {
+	if h, ok := dto.(pg.AfterCreator); ok {
+		if err := h.AfterCreate(ctx); err != nil {
+			return err
+		}
+	}
+	for _, h := range f.hooks {
+		if h.AfterCreate == nil {
+			continue
+		}
+		if err := h.AfterCreate(ctx, id, dto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fake) runQueryHooks(ctx context.Context, hook func(pg.Hooks) func(context.Context, pg.DAO) error) error {
+	for _, h := range f.hooks {
+		fn := hook(h)
+		if fn == nil {
+			continue
+		}
+		if err := fn(ctx, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fake) afterFind(ctx context.Context, dto interface{}) error {
+	if h, ok := dto.(pg.AfterFinder); ok {
+		if err := h.AfterFind(ctx); err != nil {
+			return err
+		}
+	}
+	for _, h := range f.hooks {
+		if h.AfterFind == nil {
+			continue
+		}
+		if err := h.AfterFind(ctx, dto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterFindAll runs AfterFind hooks for every element of the slice.
+func (f *fake) afterFindAll(ctx context.Context, slice reflect.Value) error {
+	for i := 0; i < slice.Len(); i++ {
+		elem := slice.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		if err := f.afterFind(ctx, elem.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package pgdaotest
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"strconv"
+
+	pg "github.com/olegfomenko/pg-dao"
+)
+
+// jsonPredicate evaluates JSON filters over decoded column values the way jsonb operators do.
+// SQL/JSON path queries are not supported.
+func jsonPredicate(cond pg.JSONCondition) (predicate, bool) {
+	switch cond.Op {
+	case pg.JSONOpContains:
+		want, ok := jsonOf(pg.JSON{V: cond.Value})
+		return func(r row) bool {
+			doc, isSet := jsonOf(r[cond.Column])
+			return ok && isSet && jsonContains(doc, want, true)
+		}, true
+	case pg.JSONOpHasKey, pg.JSONOpHasAnyKey, pg.JSONOpHasAllKeys:
+		all := cond.Op != pg.JSONOpHasAnyKey
+		return func(r row) bool {
+			doc, ok := jsonOf(r[cond.Column])
+			if !ok {
+				return false
+			}
+			for _, key := range cond.Keys {
+				if jsonHasKey(doc, key) != all {
+					return !all
+				}
+			}
+			return all
+		}, true
+	case pg.JSONOpPathEq, pg.JSONOpPathNotEq:
+		eq := cond.Op == pg.JSONOpPathEq
+		if s, ok := cond.Value.(string); ok {
+			return func(r row) bool {
+				text, ok := jsonText(jsonAt(r[cond.Column], cond.Path))
+				return ok && (text == s) == eq
+			}, true
+		}
+		want, ok := jsonOf(pg.JSON{V: cond.Value})
+		return func(r row) bool {
+			val, isSet := jsonAt(r[cond.Column], cond.Path)
+			return ok && isSet && reflect.DeepEqual(val, want) == eq
+		}, true
+	case pg.JSONOpPathIsNull:
+		return func(r row) bool {
+			_, ok := jsonText(jsonAt(r[cond.Column], cond.Path))
+			return !ok
+		}, true
+	}
+	return nil, false
+}
+
+// jsonOf decodes a jsonb column value to the generic representation of encoding/json, ok is false for NULL.
+// Strings and byte slices are JSON documents, like text passed to a jsonb column.
+func jsonOf(val interface{}) (result interface{}, ok bool) {
+	if valuer, ok := val.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, false
+		}
+		val = v
+	}
+
+	var data []byte
+	switch v := val.(type) {
+	case nil:
+		return nil, false
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	default:
+		rv := reflect.ValueOf(val)
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			if rv.IsNil() {
+				return nil, false
+			}
+		}
+		var err error
+		if data, err = json.Marshal(val); err != nil {
+			return nil, false
+		}
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+// jsonContains reports whether doc contains val: objects contain subsets of their pairs, arrays contain
+// arrays of their elements, and a top-level array contains its scalar elements.
+func jsonContains(doc, val interface{}, top bool) bool {
+	switch val := val.(type) {
+	case map[string]interface{}:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, v := range val {
+			if item, ok := obj[key]; !ok || !jsonContains(item, v, false) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		list, ok := doc.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, v := range val {
+			if !jsonHasElem(list, v, false) {
+				return false
+			}
+		}
+		return true
+	}
+	if list, ok := doc.([]interface{}); ok && top {
+		return jsonHasElem(list, val, true)
+	}
+	return reflect.DeepEqual(doc, val)
+}
+
+func jsonHasElem(list []interface{}, val interface{}, scalar bool) bool {
+	for _, item := range list {
+		if scalar && reflect.DeepEqual(item, val) || !scalar && jsonContains(item, val, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonHasKey reports whether key is a key of the object, a string element of the array or the string doc.
+func jsonHasKey(doc interface{}, key string) bool {
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		_, ok := doc[key]
+		return ok
+	case []interface{}:
+		for _, item := range doc {
+			if s, ok := item.(string); ok && s == key {
+				return true
+			}
+		}
+	case string:
+		return doc == key
+	}
+	return false
+}
+
+// jsonAt returns the value of the jsonb column value at path like #> does, ok is false if there is none.
+// Array indexes are numbers, negative ones count from the end.
+func jsonAt(val interface{}, path []string) (interface{}, bool) {
+	doc, ok := jsonOf(val)
+	if !ok {
+		return nil, false
+	}
+	for _, key := range path {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			if doc, ok = v[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, false
+			}
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return nil, false
+			}
+			doc = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// jsonText returns the text of the value like #>> does, ok is false for a missing value and JSON null.
+func jsonText(val interface{}, ok bool) (string, bool) {
+	if !ok || val == nil {
+		return "", false
+	}
+	if s, isString := val.(string); isString {
+		return s, true
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
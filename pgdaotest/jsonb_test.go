@@ -0,0 +1,81 @@
+package pgdaotest
+
+import (
+	"reflect"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	pg "github.com/olegfomenko/pg-dao"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type jsonEntry struct {
+	ID   int64                  `db:"id"`
+	Name string                 `db:"name"`
+	Meta map[string]interface{} `db:"meta"`
+}
+
+func TestFakeJSONFilters(t *testing.T) {
+	q := NewDAO(NewStore(), "entries")
+	_, err := q.BulkCreate([]jsonEntry{
+		{Name: "a", Meta: map[string]interface{}{
+			"plan":    "pro",
+			"tags":    []string{"go", "sql"},
+			"limits":  []int{10, 20},
+			"address": map[string]string{"city": "Kyiv"},
+		}},
+		{Name: "b", Meta: map[string]interface{}{"plan": "free", "trial": true, "limits": []int{5}, "note": nil}},
+		{Name: "c"},
+		{Name: "d", Meta: map[string]interface{}{"plan": "pro", "address": map[string]string{"city": "Lviv"}}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		cond     sq.Sqlizer
+		expected []string
+	}{
+		{name: "contains pair", cond: pg.JSONContains("meta", map[string]string{"plan": "pro"}),
+			expected: []string{"a", "d"}},
+		{name: "contains element", cond: pg.JSONContains("meta", map[string]interface{}{"tags": []string{"sql"}}),
+			expected: []string{"a"}},
+		{name: "contains nested", cond: pg.JSONContains("meta", map[string]interface{}{
+			"address": map[string]string{"city": "Kyiv"},
+		}), expected: []string{"a"}},
+		{name: "contains mismatch", cond: pg.JSONContains("meta", map[string]interface{}{"limits": 5})},
+		{name: "has key", cond: pg.JSONHasKey("meta", "trial"), expected: []string{"b"}},
+		{name: "has any key", cond: pg.JSONHasAnyKey("meta", "trial", "address"), expected: []string{"a", "b", "d"}},
+		{name: "has all keys", cond: pg.JSONHasAllKeys("meta", "plan", "address"), expected: []string{"a", "d"}},
+		{name: "path text", cond: pg.JSONPath("meta", "$.address.city").Eq("Kyiv"), expected: []string{"a"}},
+		{name: "path not equal", cond: pg.JSONPath("meta", "address.city").NotEq("Kyiv"), expected: []string{"d"}},
+		{name: "path number", cond: pg.JSONPath("meta", "$.limits[0]").Eq(10), expected: []string{"a"}},
+		{name: "path number text", cond: pg.JSONPath("meta", "$.limits[0]").Eq("5"), expected: []string{"b"}},
+		{name: "path last index", cond: pg.JSONPath("meta", "limits.-1").Eq(20), expected: []string{"a"}},
+		{name: "path boolean", cond: pg.JSONPath("meta", "trial").Eq(true), expected: []string{"b"}},
+		{name: "path null", cond: pg.JSONPath("meta", "note").IsNull(), expected: []string{"a", "b", "c", "d"}},
+		{name: "path missing", cond: pg.JSONPath("meta", "trial").IsNull(), expected: []string{"a", "c", "d"}},
+		{name: "combined", cond: sq.And{pg.JSONHasKey("meta", "plan"), sq.NotEq{"name": "a"}},
+			expected: []string{"b", "d"}},
+	}
+	for _, c := range cases {
+		var list []jsonEntry
+		if err := q.New().Filter(c.cond).OrderByAsc("name").Select(&list); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var names []string
+		for _, e := range list {
+			names = append(names, e.Name)
+		}
+		if !reflect.DeepEqual(names, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, names)
+		}
+	}
+
+	var list []jsonEntry
+	err = q.New().Filter(pg.JSONPathExists("meta", "$.plan")).Select(&list)
+	if errors.Cause(err) != ErrUnsupported {
+		t.Fatalf("path exists: expected ErrUnsupported, got %v", err)
+	}
+}
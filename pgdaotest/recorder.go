@@ -0,0 +1,196 @@
+package pgdaotest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	pg "github.com/olegfomenko/pg-dao"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// Statement is a statement executed through Recorder. SQL uses $n placeholders.
+type Statement struct {
+	SQL  string
+	Args []interface{}
+}
+
+func (s Statement) String() string {
+	return fmt.Sprintf("%s %v", s.SQL, s.Args)
+}
+
+// Recorder is a pg.Executor recording statements built by the real DAO instead of executing them.
+// Get and Select receive canned results provided with Return, exec statements affect one row
+// unless configured with ReturnRowsAffected. Transactions are recorded as BEGIN, COMMIT and ROLLBACK.
+type Recorder struct {
+	mu           sync.Mutex
+	statements   []Statement
+	expected     []Statement
+	results      []interface{}
+	rowsAffected []int64
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// DAO returns a real pg.DAO executing statements with the Recorder.
+func (r *Recorder) DAO(tableName string, opts ...pg.Option) pg.DAO {
+	return pg.NewDAO(nil, tableName, append(opts, pg.WithExecutor(r))...)
+}
+
+// Return enqueues results for subsequent Get and Select calls, in order. A result has to be
+// assignable to the destination, e.g. Entry for Get(&entry), []Entry for Select(&entries)
+// or int64 for the id returned by Create. Nil result makes Get report no rows.
+func (r *Recorder) Return(results ...interface{}) *Recorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, results...)
+	return r
+}
+
+// ReturnRowsAffected enqueues numbers of rows affected by subsequent exec statements.
+func (r *Recorder) ReturnRowsAffected(rows ...int64) *Recorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rowsAffected = append(r.rowsAffected, rows...)
+	return r
+}
+
+// Expect adds a statement expected to be executed. Integer and float arguments
+// are compared regardless of their exact types.
+func (r *Recorder) Expect(sql string, args ...interface{}) *Recorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expected = append(r.expected, Statement{SQL: sql, Args: args})
+	return r
+}
+
+// Verify reports an error to t unless all expected statements were executed in the order of expectation.
+// Other statements may be executed in between.
+func (r *Recorder) Verify(t testing.TB) {
+	t.Helper()
+
+	statements := r.Statements()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := 0
+	for _, expected := range r.expected {
+		for i < len(statements) && !statementsEqual(expected, statements[i]) {
+			i++
+		}
+		if i == len(statements) {
+			t.Errorf("expected statement was not executed: %s\nexecuted statements:\n%s", expected, formatStatements(statements))
+			return
+		}
+		i++
+	}
+}
+
+// Statements returns all recorded statements.
+func (r *Recorder) Statements() []Statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Statement(nil), r.statements...)
+}
+
+// Reset forgets recorded statements, expectations and pending results.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements, r.expected, r.results, r.rowsAffected = nil, nil, nil, nil
+}
+
+func (r *Recorder) GetContext(_ context.Context, dest interface{}, query string, args ...interface{}) error {
+	result, ok := r.record(query, args)
+	if !ok || result == nil {
+		return sql.ErrNoRows
+	}
+	return assign(reflect.ValueOf(dest).Elem(), result)
+}
+
+func (r *Recorder) SelectContext(_ context.Context, dest interface{}, query string, args ...interface{}) error {
+	result, ok := r.record(query, args)
+	if !ok || result == nil {
+		return nil
+	}
+	return assign(reflect.ValueOf(dest).Elem(), result)
+}
+
+func (r *Recorder) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.statements = append(r.statements, Statement{SQL: query, Args: args})
+	rows := int64(1)
+	if len(r.rowsAffected) > 0 {
+		rows, r.rowsAffected = r.rowsAffected[0], r.rowsAffected[1:]
+	}
+	return execResult(rows), nil
+}
+
+func (r *Recorder) TransactionWithOptions(_ *sql.TxOptions, fn func() error) error {
+	r.record("BEGIN", nil)
+	if err := fn(); err != nil {
+		r.record("ROLLBACK", nil)
+		return errors.Wrap(err, "failed to execute statements")
+	}
+	r.record("COMMIT", nil)
+	return nil
+}
+
+// record stores the statement and pops the next canned result.
+func (r *Recorder) record(query string, args []interface{}) (interface{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.statements = append(r.statements, Statement{SQL: query, Args: args})
+	if query == "BEGIN" || query == "COMMIT" || query == "ROLLBACK" || len(r.results) == 0 {
+		return nil, false
+	}
+
+	result := r.results[0]
+	r.results = r.results[1:]
+	return result, true
+}
+
+type execResult int64
+
+func (r execResult) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported by PostgreSQL driver")
+}
+
+func (r execResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+func statementsEqual(expected, actual Statement) bool {
+	if strings.Join(strings.Fields(expected.SQL), " ") != strings.Join(strings.Fields(actual.SQL), " ") {
+		return false
+	}
+	if len(expected.Args) != len(actual.Args) {
+		return false
+	}
+	for i := range expected.Args {
+		if normalize(expected.Args[i]) == nil && normalize(actual.Args[i]) == nil {
+			continue
+		}
+		if c, ok := compare(expected.Args[i], actual.Args[i]); !ok || c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func formatStatements(statements []Statement) string {
+	lines := make([]string, len(statements))
+	for i, s := range statements {
+		lines[i] = "\t" + s.String()
+	}
+	return strings.Join(lines, "\n")
+}
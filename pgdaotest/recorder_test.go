@@ -0,0 +1,53 @@
+package pgdaotest
+
+import (
+	"reflect"
+	"testing"
+)
+
+type recorderMeta struct {
+	Source string `json:"source"`
+	Rank   int    `json:"rank"`
+}
+
+type recorderEntry struct {
+	ID     int64             `db:"id"`
+	Tags   []string          `db:"tags"`
+	Scores []int32           `db:"scores,array"`
+	Attrs  map[string]string `db:"attrs,hstore"`
+	Extra  map[string]int    `db:"extra"`
+	Meta   recorderMeta      `db:"meta,json"`
+	Note   *recorderMeta     `db:"note,json"`
+}
+
+func TestRecorderReturnsCodecFields(t *testing.T) {
+	entry := recorderEntry{
+		ID:     1,
+		Tags:   []string{"a", "b c"},
+		Scores: []int32{3, 1},
+		Attrs:  map[string]string{"k": "v"},
+		Extra:  map[string]int{"x": 1},
+		Meta:   recorderMeta{Source: "api", Rank: 2},
+	}
+
+	r := NewRecorder().Return(entry, []recorderEntry{entry, {ID: 2}})
+	q := r.DAO("entries")
+
+	var got recorderEntry
+	ok, err := q.FilterByID(1).Get(&got)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !ok || !reflect.DeepEqual(got, entry) {
+		t.Fatalf("get: expected %+v, got %+v", entry, got)
+	}
+
+	var list []recorderEntry
+	if err := q.New().Select(&list); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	expected := []recorderEntry{entry, {ID: 2}}
+	if !reflect.DeepEqual(list, expected) {
+		t.Fatalf("select: expected %+v, got %+v", expected, list)
+	}
+}
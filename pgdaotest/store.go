@@ -0,0 +1,60 @@
+// Package pgdaotest provides helpers for testing code built on top of pg-dao.
+package pgdaotest
+
+import (
+	"sync"
+)
+
+type row map[string]interface{}
+
+type table struct {
+	rows   []row
+	nextID int64
+}
+
+// Store keeps rows of in-memory tables. DAOs created over the same Store share data and transactions.
+type Store struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+func NewStore() *Store {
+	return &Store{
+		tables: make(map[string]*table),
+	}
+}
+
+// table returns the table with provided name creating it if needed. Must be called with mu held.
+func (s *Store) table(name string) *table {
+	t, ok := s.tables[name]
+	if !ok {
+		t = &table{nextID: 1}
+		s.tables[name] = t
+	}
+	return t
+}
+
+// snapshot returns a copy of all tables to restore on transaction rollback.
+func (s *Store) snapshot() map[string]*table {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[string]*table, len(s.tables))
+	for name, t := range s.tables {
+		rows := make([]row, len(t.rows))
+		for i, r := range t.rows {
+			rows[i] = make(row, len(r))
+			for col, val := range r {
+				rows[i][col] = val
+			}
+		}
+		snapshot[name] = &table{rows: rows, nextID: t.nextID}
+	}
+	return snapshot
+}
+
+func (s *Store) restore(snapshot map[string]*table) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables = snapshot
+}
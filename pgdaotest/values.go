@@ -0,0 +1,409 @@
+package pgdaotest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"time"
+
+	pg "github.com/olegfomenko/pg-dao"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// normalize converts a value to the representation used for comparison:
+// driver.Valuer is resolved, integers become int64, unsigned integers uint64 and floats float64.
+func normalize(val interface{}) interface{} {
+	if valuer, ok := val.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err == nil {
+			val = v
+		}
+	}
+
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return normalize(v.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	}
+	return val
+}
+
+// compare returns -1, 0 or 1 comparing a and b the way PostgreSQL would.
+// ok is false if values are not comparable, e.g. one of them is NULL.
+func compare(a, b interface{}) (result int, ok bool) {
+	a, b = normalize(a), normalize(b)
+	if a == nil || b == nil {
+		return 0, false
+	}
+
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return cmpInt(x, y), true
+		case uint64:
+			if x < 0 {
+				return -1, true
+			}
+			return cmpUint(uint64(x), y), true
+		case float64:
+			return cmpFloat(float64(x), y), true
+		}
+	case uint64:
+		switch y := b.(type) {
+		case uint64:
+			return cmpUint(x, y), true
+		case int64:
+			if y < 0 {
+				return 1, true
+			}
+			return cmpUint(x, uint64(y)), true
+		case float64:
+			return cmpFloat(float64(x), y), true
+		}
+	case float64:
+		switch y := b.(type) {
+		case float64:
+			return cmpFloat(x, y), true
+		case int64:
+			return cmpFloat(x, float64(y)), true
+		case uint64:
+			return cmpFloat(x, float64(y)), true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			}
+			return 0, true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case !x:
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return 0, true
+	}
+	return 0, false
+}
+
+func cmpInt(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func cmpUint(x, y uint64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func cmpFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// fields returns addressable struct fields of v by their db tag names, including inlined and embedded structs.
+// If options is not nil, it is filled with db tag options of the fields.
+func fields(v reflect.Value, result map[string]reflect.Value, options map[string][]string) map[string]reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		parts := strings.Split(f.Tag.Get("db"), ",")
+		name := parts[0]
+		if name == "-" {
+			continue
+		}
+
+		fv := v.Field(i)
+		isStruct := fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{})
+		inline := f.Anonymous || hasOption(parts[1:], pg.TagInline)
+		if isStruct && name == "" && inline && !fv.Addr().Type().Implements(scannerType) {
+			fields(fv, result, options)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		result[name] = fv
+		if options != nil {
+			options[name] = parts[1:]
+		}
+	}
+	return result
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	bytesType   = reflect.TypeOf([]byte(nil))
+)
+
+// encode returns the value the DAO writes for a field with db tag options stored
+// as json, array or hstore.
+func encode(v reflect.Value, options []string) interface{} {
+	switch {
+	case hasOption(options, pg.TagJSON):
+		return pg.JSON{V: v.Interface()}
+	case hasOption(options, pg.TagArray):
+		return pg.Array(v.Interface())
+	case hasOption(options, pg.TagHstore):
+		if v.IsNil() {
+			return pg.Hstore(nil)
+		}
+		h := make(pg.Hstore, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			val := reflect.Indirect(iter.Value())
+			if !val.IsValid() {
+				h[iter.Key().String()] = nil
+				continue
+			}
+			s := val.String()
+			h[iter.Key().String()] = &s
+		}
+		return h
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		return pg.Array(v.Interface())
+	}
+	return pg.JSON{V: v.Interface()}
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// assign sets struct field to the value stored in the table.
+func assign(field reflect.Value, val interface{}) error {
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		if valuer, ok := val.(driver.Valuer); ok {
+			v, err := valuer.Value()
+			if err != nil {
+				return errors.Wrap(err, "failed to get value")
+			}
+			val = v
+		}
+		return scanner.Scan(val)
+	}
+	if valuer, ok := val.(driver.Valuer); ok && field.Type() == bytesType {
+		v, err := valuer.Value()
+		if err != nil {
+			return errors.Wrap(err, "failed to get value")
+		}
+		val = v
+	}
+
+	v := reflect.ValueOf(val)
+	if !v.IsValid() {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		if field.Kind() != reflect.Ptr {
+			v = v.Elem()
+		}
+	}
+
+	if field.Kind() == reflect.Ptr && v.Kind() != reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := assign(ptr.Elem(), val); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	switch {
+	case v.Type().AssignableTo(field.Type()):
+		field.Set(v)
+	case v.Type().ConvertibleTo(field.Type()):
+		field.Set(v.Convert(field.Type()))
+	case v.Kind() == reflect.Struct && field.Kind() == reflect.Struct:
+		// e.g. a canned DTO returned into a differently shaped scan destination,
+		// which receives json, array and hstore columns as []byte
+		src := reflect.New(v.Type()).Elem()
+		src.Set(v)
+		dest := fields(field, make(map[string]reflect.Value), nil)
+		options := make(map[string][]string)
+		values := make(row)
+		for col, f := range fields(src, make(map[string]reflect.Value), options) {
+			values[col] = f.Interface()
+			if d, ok := dest[col]; ok && d.Type() == bytesType && f.Type() != bytesType {
+				values[col] = encode(f, options[col])
+			}
+		}
+		return scanRow(field, values)
+	case v.Kind() == reflect.Slice && field.Kind() == reflect.Slice:
+		list := reflect.MakeSlice(field.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := assign(list.Index(i), v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		field.Set(list)
+	default:
+		return errors.Errorf("can not assign %s to %s", v.Type(), field.Type())
+	}
+	return nil
+}
+
+// scanRow fills dest struct with row values.
+func scanRow(dest reflect.Value, r row) error {
+	for col, field := range fields(dest, make(map[string]reflect.Value), nil) {
+		val, ok := r[col]
+		if !ok {
+			continue
+		}
+		if err := assign(field, val); err != nil {
+			return errors.Wrap(err, "failed to assign column", map[string]interface{}{
+				"column": col,
+			})
+		}
+	}
+	return nil
+}
+
+// arrayOf returns the array column value cur as a slice, a NULL array becomes a nil slice of val elements.
+// val has to be of the element type class, e.g. any integer for integer arrays, like PostgreSQL requires
+// of array_append and array_remove arguments.
+func arrayOf(cur, val interface{}) (reflect.Value, error) {
+	if val == nil {
+		return reflect.Value{}, errors.Wrap(ErrUnsupported, "NULL array elements are not supported")
+	}
+	if cur == nil {
+		return reflect.Zero(reflect.SliceOf(reflect.TypeOf(val))), nil
+	}
+	list := reflect.ValueOf(cur)
+	if list.Kind() != reflect.Slice {
+		return reflect.Value{}, errors.Errorf("%T is not an array", cur)
+	}
+	if !elemOf(list.Type().Elem(), reflect.TypeOf(val)) {
+		return reflect.Value{}, errors.Errorf("can not use %T as element of %T", val, cur)
+	}
+	return list, nil
+}
+
+// elemOf reports whether values of typ are stored in arrays of elem without loss.
+func elemOf(elem, typ reflect.Type) bool {
+	switch class, valClass := kindClass(elem.Kind()), kindClass(typ.Kind()); {
+	case class == "float":
+		return valClass == "float" || valClass == "int"
+	case class != "":
+		return class == valClass
+	}
+	return typ.ConvertibleTo(elem) && typ.Kind() == elem.Kind()
+}
+
+func kindClass(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	}
+	return ""
+}
+
+// add returns cur + sign * n, integers stay integers. NULL stays NULL like in SQL.
+func add(cur, n interface{}, sign int64) (interface{}, error) {
+	x, y := normalize(cur), normalize(n)
+	if x == nil {
+		return nil, nil
+	}
+
+	xi, xInt := toInt(x)
+	yi, yInt := toInt(y)
+	if xInt && yInt {
+		return xi + sign*yi, nil
+	}
+
+	xf, xOk := toFloat(x)
+	yf, yOk := toFloat(y)
+	if !xOk || !yOk {
+		return nil, errors.Errorf("can not add %T to %T", n, cur)
+	}
+	return xf + float64(sign)*yf, nil
+}
+
+func toInt(val interface{}) (int64, bool) {
+	switch v := val.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	if v, ok := val.(float64); ok {
+		return v, true
+	}
+	v, ok := toInt(val)
+	return float64(v), ok
+}
+
+func isZero(val interface{}) bool {
+	return val == nil || reflect.ValueOf(val).IsZero()
+}
@@ -6,10 +6,10 @@ import (
 	goerr "errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
-	"github.com/fatih/structs"
 	"gitlab.com/distributed_lab/kit/pgdb"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 )
@@ -17,96 +17,267 @@ import (
 type dao struct {
 	tableName string
 	db        *pgdb.DB
+	executor  Executor
+	opts      *options
 	sql       sq.SelectBuilder
 	upd       sq.UpdateBuilder
 	dlt       sq.DeleteBuilder
-}
+	updWhere  sq.And
+	dltWhere  sq.And
+	updSet    bool
+	updCols   map[string]bool
+	fullTable bool
+	count     bool
+	version   *int64
+	dryRun    bool
+	// inTx is set while a transaction of the session runs, writes that need one reuse it.
+	inTx bool
+	// err is the first error of building the statement, returned on its execution.
+	err error
+}
+
+func NewDAO(db *pgdb.DB, tableName string, opts ...Option) DAO {
+	return newDAO(db, tableName, newOptions(opts))
+}
+
+func newDAO(db *pgdb.DB, tableName string, opts *options) *dao {
+	var executor Executor = dbExecutor{db: db}
+	if opts.executor != nil {
+		executor = opts.executor
+	}
 
-func NewDAO(db *pgdb.DB, tableName string) DAO {
 	return &dao{
 		tableName: tableName,
 		db:        db,
+		executor:  executor,
+		opts:      opts,
 		sql:       sq.Select(tableName + ".*").From(tableName),
 		upd:       sq.Update(tableName),
 		dlt:       sq.Delete(tableName),
+		inTx:      opts.inTx,
 	}
 }
 
 func (d *dao) Clone() DAO {
-	return &dao{
-		tableName: d.tableName,
-		db:        d.db.Clone(),
-		sql:       sq.Select(d.tableName + ".*").From(d.tableName),
-		upd:       sq.Update(d.tableName),
-		dlt:       sq.Delete(d.tableName),
+	db := d.db
+	if db != nil {
+		db = db.Clone()
 	}
+	return newDAO(db, d.tableName, d.opts)
 }
 
 func (d *dao) New() DAO {
-	return &dao{
-		tableName: d.tableName,
-		db:        d.db,
-		sql:       sq.Select(d.tableName + ".*").From(d.tableName),
-		upd:       sq.Update(d.tableName),
-		dlt:       sq.Delete(d.tableName),
-	}
+	n := newDAO(d.db, d.tableName, d.opts)
+	n.dryRun = d.dryRun
+	n.inTx = d.inTx
+	return n
 }
 
 func (d *dao) Count() DAO {
-	return &dao{
-		tableName: d.tableName,
-		db:        d.db,
-		sql:       sq.Select("count(*)").From(d.tableName),
-		upd:       sq.Update(d.tableName),
-		dlt:       sq.Delete(d.tableName),
-	}
+	c := newDAO(d.db, d.tableName, d.opts)
+	c.dryRun = d.dryRun
+	c.inTx = d.inTx
+	c.sql = sq.Select("count(*)").From(d.tableName)
+	c.count = true
+	return c
 }
 
 func (d *dao) Create(dto interface{}) (int64, error) {
 	return d.CreateCtx(context.TODO(), dto)
 }
 
-func (d *dao) CreateCtx(ctx context.Context, dto interface{}) (int64, error) {
-	clauses := structs.Map(dto)
+func (d *dao) CreateCtx(ctx context.Context, dto interface{}) (_ int64, err error) {
+	defer d.observe(ctx, OpCreate, time.Now(), &err)
+
+	if err := d.beforeCreate(ctx, dto); err != nil {
+		return 0, err
+	}
+
+	clauses, err := d.createValues(dto)
+	if err != nil {
+		return 0, err
+	}
 
 	var id int64
 	stmt := sq.Insert(d.tableName).SetMap(clauses).Suffix("returning id")
-	err := d.db.GetContext(ctx, &id, stmt)
+	if d.dryRun {
+		return 0, newDryRunStatement(stmt)
+	}
+
+	if !d.opts.audit {
+		if err := d.get(ctx, &id, stmt); err != nil {
+			return 0, err
+		}
+		return id, d.afterCreate(ctx, id, dto)
+	}
+
+	// AfterCreate hooks run in the transaction of the audit entry, so their errors roll the row back
+	err = d.atomic(func() error {
+		if err := d.get(ctx, &id, stmt); err != nil {
+			return err
+		}
+		if err := d.auditCreate(ctx, id); err != nil {
+			return err
+		}
+		return d.afterCreate(ctx, id, dto)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (d *dao) BulkCreate(dtos interface{}) ([]int64, error) {
+	return d.BulkCreateCtx(context.TODO(), dtos)
+}
+
+// BulkCreateCtx inserts the slice of dtos with a single statement and returns ids in the order of dtos.
+// Columns written for some of dtos only are set to DEFAULT for the others. Hooks, timestamps and audit
+// are applied to every dto the same way CreateCtx applies them.
+func (d *dao) BulkCreateCtx(ctx context.Context, dtos interface{}) (_ []int64, err error) {
+	defer d.observe(ctx, OpCreate, time.Now(), &err)
+
+	list := reflect.ValueOf(dtos)
+	if list.Kind() != reflect.Slice {
+		return nil, errors.New("argument is not a slice")
+	}
+	if list.Len() == 0 {
+		return nil, nil
+	}
 
-	return id, err
+	rows := make([]map[string]interface{}, list.Len())
+	seen := make(map[string]bool)
+	var columns []string
+	for i := range rows {
+		dto := list.Index(i).Interface()
+		if err := d.beforeCreate(ctx, dto); err != nil {
+			return nil, err
+		}
+		if rows[i], err = d.createValues(dto); err != nil {
+			return nil, err
+		}
+		for col := range rows[i] {
+			if !seen[col] {
+				seen[col] = true
+				columns = append(columns, col)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		columns = []string{IdColumn}
+	}
+	sort.Strings(columns)
+
+	stmt := sq.Insert(d.tableName).Columns(columns...).Suffix("returning id")
+	for _, row := range rows {
+		args := make([]interface{}, len(columns))
+		for i, col := range columns {
+			val, ok := row[col]
+			if !ok {
+				val = sq.Expr("DEFAULT")
+			}
+			args[i] = val
+		}
+		stmt = stmt.Values(args...)
+	}
+	if d.dryRun {
+		return nil, newDryRunStatement(stmt)
+	}
+
+	var ids []int64
+	insert := func() error {
+		if err := d.query(ctx, &ids, stmt); err != nil {
+			return err
+		}
+		if len(ids) != len(rows) {
+			return errors.From(errors.New("unexpected number of inserted rows"), map[string]interface{}{
+				"rows":     len(rows),
+				"inserted": len(ids),
+			})
+		}
+		// rows of a single INSERT ... VALUES statement are returned in the order of values
+		for i, id := range ids {
+			if err := d.auditCreate(ctx, id); err != nil {
+				return err
+			}
+			if err := d.afterCreate(ctx, id, list.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !d.opts.audit {
+		return ids, insert()
+	}
+	if err := d.atomic(insert); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// createValues returns column values Create writes for dto with managed columns set.
+func (d *dao) createValues(dto interface{}) (map[string]interface{}, error) {
+	clauses, err := columnValues(dto, d.opts.structsTags, true, true)
+	if err != nil {
+		return nil, err
+	}
+	if col := d.opts.createdAt; col != "" && isZero(clauses[col]) {
+		clauses[col] = d.opts.now()
+	}
+	if col := d.opts.version; col != "" && isZero(clauses[col]) {
+		clauses[col] = 1
+	}
+	return clauses, nil
 }
 
 func (d *dao) Get(dto interface{}) (bool, error) {
 	return d.GetCtx(context.TODO(), dto)
 }
 
-func (d *dao) GetCtx(ctx context.Context, dto interface{}) (bool, error) {
+func (d *dao) GetCtx(ctx context.Context, dto interface{}) (_ bool, err error) {
+	defer d.observe(ctx, OpGet, time.Now(), &err)
+
 	if reflect.ValueOf(dto).Type().Kind() != reflect.Ptr {
 		return false, errors.New("argument is not a pointer")
 	}
-	err := d.db.GetContext(ctx, dto, d.sql)
+	if d.err != nil {
+		return false, d.err
+	}
+	err = d.get(ctx, dto, d.sql)
 	if goerr.Is(err, sql.ErrNoRows) {
 		return false, nil
 	}
+	if err != nil {
+		return true, err
+	}
 
-	return true, err
+	return true, d.afterFind(ctx, dto)
 }
 
 func (d *dao) Select(list interface{}) error {
 	return d.SelectCtx(context.TODO(), list)
 }
 
-func (d *dao) SelectCtx(ctx context.Context, list interface{}) error {
+func (d *dao) SelectCtx(ctx context.Context, list interface{}) (err error) {
+	defer d.observe(ctx, OpSelect, time.Now(), &err)
+
 	if reflect.ValueOf(list).Type().Kind() != reflect.Ptr {
 		return errors.New("argument is not a slice pointer")
 	}
+	if d.err != nil {
+		return d.err
+	}
 
-	err := d.db.SelectContext(ctx, list, d.sql)
+	err = d.query(ctx, list, d.sql)
 	if goerr.Is(err, sql.ErrNoRows) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	return d.afterFindAll(ctx, list)
 }
 
 func (d *dao) FilterByID(id int64) DAO {
@@ -115,12 +286,12 @@ func (d *dao) FilterByID(id int64) DAO {
 }
 
 func (d *dao) FilterOnlyAfter(time time.Time) DAO {
-	d.sql = d.sql.Where(sq.Gt{CreatedAtColumn: time})
+	d.sql = d.sql.Where(sq.Gt{d.opts.createdAtColumn(): time})
 	return d
 }
 
 func (d *dao) FilterOnlyBefore(time time.Time) DAO {
-	d.sql = d.sql.Where(sq.Lt{CreatedAtColumn: time})
+	d.sql = d.sql.Where(sq.Lt{d.opts.createdAtColumn(): time})
 	return d
 }
 
@@ -139,6 +310,12 @@ func (d *dao) FilterByColumn(col string, val interface{}) DAO {
 	return d
 }
 
+// Filter adds an arbitrary condition, e.g. squirrel expressions or JSON filters like JSONContains.
+func (d *dao) Filter(cond sq.Sqlizer) DAO {
+	d.sql = d.sql.Where(cond)
+	return d
+}
+
 func (d *dao) Limit(limit uint64) DAO {
 	d.sql = d.sql.Limit(limit)
 	return d
@@ -155,12 +332,72 @@ func (d *dao) OrderByAsc(col string) DAO {
 }
 
 func (d *dao) UpdateWhereID(id int64) DAO {
-	d.upd = d.upd.Where(sq.Eq{IdColumn: id})
+	d.updateWhere(sq.Eq{IdColumn: id})
+	return d
+}
+
+func (d *dao) UpdateWhereVersion(version int64) DAO {
+	d.version = &version
 	return d
 }
 
 func (d *dao) UpdateColumn(col string, val interface{}) DAO {
 	d.upd = d.upd.Set(col, val)
+	d.updSet = true
+	if d.updCols == nil {
+		d.updCols = make(map[string]bool)
+	}
+	d.updCols[col] = true
+	return d
+}
+
+// UpdateExpr sets the column to an expression that may reference other columns or call functions,
+// e.g. sq.Expr("balance - ?", amount) or sq.Expr("now()").
+func (d *dao) UpdateExpr(col string, expr sq.Sqlizer) DAO {
+	return d.UpdateColumn(col, expr)
+}
+
+// Increment adds n to the numeric column on Update: SET col = col + n.
+func (d *dao) Increment(col string, n interface{}) DAO {
+	return d.UpdateExpr(col, sq.Expr(col+" + ?", n))
+}
+
+// Decrement subtracts n from the numeric column on Update: SET col = col - n.
+func (d *dao) Decrement(col string, n interface{}) DAO {
+	return d.UpdateExpr(col, sq.Expr(col+" - ?", n))
+}
+
+// UpdateDTO sets columns written by Create from dto, except id and columns managed by DAO.
+// If versioning is enabled, the version carried by dto is checked as with UpdateWhereVersion.
+func (d *dao) UpdateDTO(dto interface{}) DAO {
+	values, err := columnValues(dto, d.opts.structsTags, false, true)
+	if err != nil {
+		d.setErr(err)
+		return d
+	}
+
+	if col := d.opts.version; col != "" {
+		if val, ok := values[col]; ok {
+			version, err := toInt64(val)
+			if err != nil {
+				d.setErr(errors.Wrap(err, "invalid version", map[string]interface{}{"column": col}))
+				return d
+			}
+			d.version = &version
+		}
+	}
+	for _, col := range []string{IdColumn, d.opts.createdAt, d.opts.updatedAt, d.opts.version} {
+		delete(values, col)
+	}
+
+	cols := make([]string, 0, len(values))
+	for col := range values {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for _, col := range cols {
+		d.UpdateColumn(col, values[col])
+	}
 	return d
 }
 
@@ -168,28 +405,146 @@ func (d *dao) Update() error {
 	return d.UpdateCtx(context.TODO())
 }
 
+// UpdateCtx updates rows matching update conditions. It returns ErrNotFound, or ErrStaleVersion
+// if versioning is enabled, when no rows are updated.
 func (d *dao) UpdateCtx(ctx context.Context) error {
-	res, err := d.db.ExecWithResultContext(ctx, d.upd)
+	_, err := d.update(ctx, false)
+	return err
+}
+
+func (d *dao) BulkUpdate() (int64, error) {
+	return d.BulkUpdateCtx(context.TODO())
+}
+
+// BulkUpdateCtx updates rows matching update conditions and returns the number of updated rows.
+// Unlike UpdateCtx it does not fail when no rows are updated and requires no version: if versioning
+// is enabled, versions of updated rows are incremented and checked only if set by UpdateWhereVersion.
+func (d *dao) BulkUpdateCtx(ctx context.Context) (int64, error) {
+	return d.update(ctx, true)
+}
+
+func (d *dao) update(ctx context.Context, bulk bool) (rows int64, err error) {
+	defer d.observe(ctx, OpUpdate, time.Now(), &err)
+
+	err = d.runQueryHooks(ctx, func(h Hooks) func(context.Context, DAO) error {
+		return h.BeforeUpdate
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	upd, where, err := d.buildUpdate(bulk)
 	if err != nil {
-		return errors.Wrap(err, "unable to update row")
+		return 0, err
+	}
+	if d.dryRun {
+		return 0, newDryRunStatement(upd)
+	}
+
+	after := func() error {
+		return d.runQueryHooks(ctx, func(h Hooks) func(context.Context, DAO) error {
+			return h.AfterUpdate
+		})
+	}
+	if !d.opts.audit {
+		if rows, err = d.execUpdate(ctx, upd, bulk); err != nil {
+			return rows, err
+		}
+		return rows, after()
+	}
+
+	err = d.atomic(func() error {
+		err := d.auditUpdate(ctx, where, func() error {
+			var err error
+			rows, err = d.execUpdate(ctx, upd, bulk)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return after()
+	})
+	return rows, err
+}
+
+// buildUpdate returns the pending update with managed columns applied and its conditions.
+func (d *dao) buildUpdate(bulk bool) (sq.UpdateBuilder, sq.And, error) {
+	upd, where := d.upd, d.updWhere
+	if d.err != nil {
+		return upd, nil, d.err
+	}
+	if len(where) == 0 && !d.fullTable {
+		return upd, nil, ErrUnfiltered
+	}
+	if col := d.opts.updatedAt; col != "" && !d.updCols[col] {
+		upd = upd.Set(col, d.opts.now())
+	}
+	if col := d.opts.version; col != "" {
+		if d.version != nil {
+			upd = upd.Where(sq.Eq{col: *d.version})
+			where = append(where, sq.Eq{col: *d.version})
+		} else if !bulk {
+			return upd, nil, errors.New("version is required to update versioned record")
+		}
+		upd = upd.Set(col, sq.Expr(col+" + 1"))
+	}
+	return upd, where, nil
+}
+
+func (d *dao) execUpdate(ctx context.Context, upd sq.UpdateBuilder, bulk bool) (int64, error) {
+	res, err := d.exec(ctx, upd)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to update row")
 	}
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		return errors.Wrap(err, "unable to get affected rows")
+		return 0, errors.Wrap(err, "unable to get affected rows")
 	}
-	if rowsAffected == 0 {
-		return ErrNotFound
+	if rowsAffected == 0 && !bulk {
+		if d.opts.version != "" {
+			return 0, ErrStaleVersion
+		}
+		return 0, ErrNotFound
 	}
-	return nil
+	return rowsAffected, nil
+}
+
+// UpdateWhere adds an arbitrary update condition, e.g. squirrel expressions or JSON filters.
+func (d *dao) UpdateWhere(cond sq.Sqlizer) DAO {
+	d.updateWhere(cond)
+	return d
+}
+
+func (d *dao) updateWhere(cond sq.Sqlizer) {
+	d.upd = d.upd.Where(cond)
+	d.updWhere = append(d.updWhere, cond)
 }
 
 func (d *dao) DeleteWhereVal(col string, val interface{}) DAO {
-	d.dlt = d.dlt.Where(sq.Eq{col: val})
+	d.deleteWhere(sq.Eq{col: val})
 	return d
 }
 
 func (d *dao) DeleteWhereID(id int64) DAO {
-	d.dlt = d.dlt.Where(sq.Eq{IdColumn: id})
+	d.deleteWhere(sq.Eq{IdColumn: id})
+	return d
+}
+
+// DeleteWhere adds an arbitrary delete condition, e.g. squirrel expressions or JSON filters.
+func (d *dao) DeleteWhere(cond sq.Sqlizer) DAO {
+	d.deleteWhere(cond)
+	return d
+}
+
+func (d *dao) deleteWhere(cond sq.Sqlizer) {
+	d.dlt = d.dlt.Where(cond)
+	d.dltWhere = append(d.dltWhere, cond)
+}
+
+// AllowFullTable lets Update and Delete without conditions affect all rows of the table
+// instead of failing with ErrUnfiltered.
+func (d *dao) AllowFullTable() DAO {
+	d.fullTable = true
 	return d
 }
 
@@ -198,39 +553,154 @@ func (d *dao) Delete() error {
 }
 
 func (d *dao) DeleteCtx(ctx context.Context) error {
-	err := d.db.ExecContext(ctx, d.dlt)
+	_, err := d.delete(ctx)
+	return err
+}
+
+func (d *dao) BulkDelete() (int64, error) {
+	return d.BulkDeleteCtx(context.TODO())
+}
+
+// BulkDeleteCtx deletes rows matching delete conditions and returns the number of deleted rows.
+func (d *dao) BulkDeleteCtx(ctx context.Context) (int64, error) {
+	return d.delete(ctx)
+}
+
+func (d *dao) delete(ctx context.Context) (rows int64, err error) {
+	defer d.observe(ctx, OpDelete, time.Now(), &err)
+
+	err = d.runQueryHooks(ctx, func(h Hooks) func(context.Context, DAO) error {
+		return h.BeforeDelete
+	})
 	if err != nil {
-		return errors.Wrap(err, "unable to delete row")
+		return 0, err
 	}
-	return nil
+	if d.err != nil {
+		return 0, d.err
+	}
+	if len(d.dltWhere) == 0 && !d.fullTable {
+		return 0, ErrUnfiltered
+	}
+	if d.dryRun {
+		return 0, newDryRunStatement(d.dlt)
+	}
+
+	after := func() error {
+		return d.runQueryHooks(ctx, func(h Hooks) func(context.Context, DAO) error {
+			return h.AfterDelete
+		})
+	}
+	if !d.opts.audit {
+		if rows, err = d.execDelete(ctx); err != nil {
+			return rows, err
+		}
+		return rows, after()
+	}
+
+	err = d.atomic(func() error {
+		err := d.auditDelete(ctx, d.dltWhere, func() error {
+			var err error
+			rows, err = d.execDelete(ctx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return after()
+	})
+	return rows, err
 }
 
+func (d *dao) execDelete(ctx context.Context) (int64, error) {
+	res, err := d.exec(ctx, d.dlt)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to delete row")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to get affected rows")
+	}
+	return rowsAffected, nil
+}
+
+// Page applies offset pagination. An unexpected order is returned as an error on execution.
 func (d *dao) Page(params pgdb.OffsetPageParams, column string) DAO {
+	if err := checkOrder(params.Order); err != nil {
+		d.setErr(err)
+		return d
+	}
 	d.sql = params.ApplyTo(d.sql, column)
 	return d
 }
 
+// Cursor applies cursor pagination by a single integer column, see SelectPage for other keys.
+// An unexpected order is returned as an error on execution.
 func (d *dao) Cursor(params pgdb.CursorPageParams, column string) DAO {
+	if err := checkOrder(params.Order); err != nil {
+		d.setErr(err)
+		return d
+	}
 	d.sql = params.ApplyTo(d.sql, column)
 	return d
 }
 
 func (d *dao) Transaction(fn func(q DAO) error) (err error) {
-	return d.db.Transaction(func() error {
-		return fn(d)
-	})
+	return d.transaction(nil, fn)
 }
 
 func (d *dao) TransactionSerializable(fn func(q DAO) error) error {
-	return d.db.TransactionWithOptions(&sql.TxOptions{Isolation: sql.LevelSerializable}, func() error {
-		return fn(d)
-	})
+	return d.transaction(&sql.TxOptions{Isolation: sql.LevelSerializable}, fn)
 }
 
 func (d *dao) TransactionWithLevel(level sql.IsolationLevel, fn func(q DAO) error) error {
-	return d.db.TransactionWithOptions(&sql.TxOptions{Isolation: level}, func() error {
-		return fn(d)
+	return d.transaction(&sql.TxOptions{Isolation: level}, fn)
+}
+
+// transaction runs fn with a copy of the DAO marked as running in the transaction, so only DAOs
+// derived from q reuse it. Other sessions of the database, e.g. DAOs derived from d by other goroutines, do not.
+func (d *dao) transaction(opts *sql.TxOptions, fn func(q DAO) error) (err error) {
+	defer d.observe(context.TODO(), OpTransaction, time.Now(), &err)
+
+	q := *d
+	if d.updCols != nil {
+		q.updCols = make(map[string]bool, len(d.updCols))
+		for col := range d.updCols {
+			q.updCols[col] = true
+		}
+	}
+	return q.runTx(opts, func() error {
+		return fn(&q)
+	})
+}
+
+// runTx runs fn in a transaction and marks the DAO as running in it meanwhile.
+func (d *dao) runTx(opts *sql.TxOptions, fn func() error) error {
+	return d.executor.TransactionWithOptions(opts, func() error {
+		inTx := d.inTx
+		d.inTx = true
+		defer func() {
+			d.inTx = inTx
+		}()
+		return fn()
+	})
+}
+
+// atomic runs fn in a transaction unless the DAO already runs in one, see WithinTransaction.
+// The error returned by fn is passed to the caller unwrapped.
+func (d *dao) atomic(fn func() error) error {
+	if d.inTx {
+		return fn()
+	}
+
+	var fnErr error
+	err := d.runTx(nil, func() error {
+		fnErr = fn()
+		return fnErr
 	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
 }
 
 func (d *dao) ExecRaw(fn func(raw *pgdb.DB) error) error {
@@ -240,3 +710,25 @@ func (d *dao) ExecRaw(fn func(raw *pgdb.DB) error) error {
 func (d *dao) ExecRawCtx(ctx context.Context, fn func(ctx context.Context, raw *pgdb.DB) error) error {
 	return fn(ctx, d.db)
 }
+
+// setErr stores the first error of building the statement.
+func (d *dao) setErr(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func toInt64(val interface{}) (int64, error) {
+	v := reflect.Indirect(reflect.ValueOf(val))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil
+	}
+	return 0, errors.Errorf("can not convert %T to int64", val)
+}
+
+func isZero(val interface{}) bool {
+	return val == nil || reflect.ValueOf(val).IsZero()
+}
@@ -0,0 +1,36 @@
+package pg_dao_test
+
+import (
+	"testing"
+
+	"github.com/olegfomenko/pg-dao/pgdaotest"
+)
+
+type bulkEntry struct {
+	ID    int64  `db:"id"`
+	Name  string `db:"name"`
+	Score int32  `db:"score,omitempty"`
+}
+
+func TestBulkCreateStatement(t *testing.T) {
+	r := pgdaotest.NewRecorder().
+		Return([]int64{4, 5}).
+		Expect("INSERT INTO entries (name,score) VALUES ($1,$2),($3,DEFAULT) returning id", "a", 3, "b")
+	q := r.DAO("entries")
+
+	ids, err := q.BulkCreate([]bulkEntry{{Name: "a", Score: 3}, {Name: "b"}})
+	if err != nil {
+		t.Fatalf("bulk create: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != 4 || ids[1] != 5 {
+		t.Fatalf("expected ids [4 5], got %v", ids)
+	}
+	r.Verify(t)
+
+	if ids, err := q.BulkCreate([]bulkEntry{}); err != nil || ids != nil {
+		t.Fatalf("expected no ids for no dtos, got %v, %v", ids, err)
+	}
+	if _, err := q.BulkCreate(bulkEntry{}); err == nil {
+		t.Fatal("expected error for a single dto")
+	}
+}
@@ -0,0 +1,167 @@
+package pg_dao
+
+import (
+	"database/sql/driver"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// Int8Range is a value of int8range column. Nil bounds are unbounded.
+// Scan a nullable column into *Int8Range.
+type Int8Range struct {
+	Lower, Upper                   *int64
+	LowerInclusive, UpperInclusive bool
+	// Empty is set for the empty range, bounds are ignored.
+	Empty bool
+}
+
+func (r Int8Range) Value() (driver.Value, error) {
+	var lower, upper string
+	if r.Lower != nil {
+		lower = strconv.FormatInt(*r.Lower, 10)
+	}
+	if r.Upper != nil {
+		upper = strconv.FormatInt(*r.Upper, 10)
+	}
+	return formatRange(r.Empty, lower, upper, r.LowerInclusive, r.UpperInclusive), nil
+}
+
+func (r *Int8Range) Scan(src interface{}) error {
+	b, err := scanRange(src)
+	if err != nil {
+		return err
+	}
+
+	*r = Int8Range{Empty: b.empty, LowerInclusive: b.lowerInc, UpperInclusive: b.upperInc}
+	for _, bound := range []struct {
+		text string
+		dest **int64
+	}{{b.lower, &r.Lower}, {b.upper, &r.Upper}} {
+		if bound.text == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(bound.text, 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "invalid int8range bound")
+		}
+		*bound.dest = &v
+	}
+	return nil
+}
+
+// TstzRange is a value of tstzrange column. Nil bounds are unbounded, infinite bounds are scanned as nil.
+// Scan a nullable column into *TstzRange.
+type TstzRange struct {
+	Lower, Upper                   *time.Time
+	LowerInclusive, UpperInclusive bool
+	// Empty is set for the empty range, bounds are ignored.
+	Empty bool
+}
+
+// tstzLayouts are formats of timestamptz output depending on the offset precision.
+var tstzLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07:00:00",
+	time.RFC3339Nano,
+}
+
+func (r TstzRange) Value() (driver.Value, error) {
+	var lower, upper string
+	if r.Lower != nil {
+		lower = strconv.Quote(r.Lower.Format(time.RFC3339Nano))
+	}
+	if r.Upper != nil {
+		upper = strconv.Quote(r.Upper.Format(time.RFC3339Nano))
+	}
+	return formatRange(r.Empty, lower, upper, r.LowerInclusive, r.UpperInclusive), nil
+}
+
+func (r *TstzRange) Scan(src interface{}) error {
+	b, err := scanRange(src)
+	if err != nil {
+		return err
+	}
+
+	*r = TstzRange{Empty: b.empty, LowerInclusive: b.lowerInc, UpperInclusive: b.upperInc}
+	for _, bound := range []struct {
+		text string
+		dest **time.Time
+	}{{b.lower, &r.Lower}, {b.upper, &r.Upper}} {
+		if bound.text == "" || bound.text == "infinity" || bound.text == "-infinity" {
+			continue
+		}
+		t, err := parseTstz(bound.text)
+		if err != nil {
+			return err
+		}
+		*bound.dest = &t
+	}
+	return nil
+}
+
+func parseTstz(s string) (time.Time, error) {
+	for _, layout := range tstzLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid tstzrange bound %q", s)
+}
+
+type rangeBounds struct {
+	empty              bool
+	lower, upper       string
+	lowerInc, upperInc bool
+}
+
+func formatRange(empty bool, lower, upper string, lowerInc, upperInc bool) string {
+	if empty {
+		return "empty"
+	}
+	open, closing := "(", ")"
+	if lowerInc {
+		open = "["
+	}
+	if upperInc {
+		closing = "]"
+	}
+	return open + lower + "," + upper + closing
+}
+
+// scanRange parses range text output, e.g. [1,10) or ("2020-01-01 00:00:00+00",).
+func scanRange(src interface{}) (rangeBounds, error) {
+	data, ok := srcBytes(src)
+	if !ok || data == nil {
+		return rangeBounds{}, errors.Errorf("can not scan %T into range", src)
+	}
+
+	s := strings.TrimSpace(string(data))
+	if s == "empty" {
+		return rangeBounds{empty: true}, nil
+	}
+	if len(s) < 3 || !strings.ContainsRune("[(", rune(s[0])) || !strings.ContainsRune("])", rune(s[len(s)-1])) {
+		return rangeBounds{}, errors.Errorf("invalid range %q", s)
+	}
+
+	parts := strings.SplitN(s[1:len(s)-1], ",", 2)
+	if len(parts) != 2 {
+		return rangeBounds{}, errors.Errorf("invalid range %q", s)
+	}
+	return rangeBounds{
+		lower:    unquoteBound(parts[0]),
+		upper:    unquoteBound(parts[1]),
+		lowerInc: s[0] == '[',
+		upperInc: s[len(s)-1] == ']',
+	}, nil
+}
+
+func unquoteBound(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return strings.Trim(s, `"`)
+}
@@ -0,0 +1,112 @@
+package pg_dao_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pg "github.com/olegfomenko/pg-dao"
+)
+
+func i64(v int64) *int64 {
+	return &v
+}
+
+func TestInt8Range(t *testing.T) {
+	cases := []struct {
+		name     string
+		src      interface{}
+		expected pg.Int8Range
+		value    string
+		fails    bool
+	}{
+		{name: "canonical", src: "[1,10)", expected: pg.Int8Range{Lower: i64(1), Upper: i64(10), LowerInclusive: true},
+			value: "[1,10)"},
+		{name: "inclusive", src: []byte("[-5,5]"),
+			expected: pg.Int8Range{Lower: i64(-5), Upper: i64(5), LowerInclusive: true, UpperInclusive: true},
+			value:    "[-5,5]"},
+		{name: "unbounded", src: "(,3)", expected: pg.Int8Range{Upper: i64(3)}, value: "(,3)"},
+		{name: "empty", src: "empty", expected: pg.Int8Range{Empty: true}, value: "empty"},
+		{name: "no brackets", src: "1,10", fails: true},
+		{name: "no comma", src: "[1)", fails: true},
+		{name: "not a number", src: "[a,b)", fails: true},
+		{name: "NULL", src: nil, fails: true},
+	}
+
+	for _, c := range cases {
+		var r pg.Int8Range
+		err := r.Scan(c.src)
+		if c.fails {
+			if err == nil {
+				t.Fatalf("%s: expected error, got %+v", c.name, r)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(r, c.expected) {
+			t.Fatalf("%s: expected %+v, got %+v", c.name, c.expected, r)
+		}
+		if val, err := r.Value(); err != nil || val != c.value {
+			t.Fatalf("%s: expected value %s, got %v, %v", c.name, c.value, val, err)
+		}
+	}
+}
+
+func TestTstzRange(t *testing.T) {
+	lower := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	upper := time.Date(2024, 1, 2, 8, 34, 5, 500000000, time.FixedZone("", 5*3600+30*60))
+
+	cases := []struct {
+		name     string
+		src      string
+		expected pg.TstzRange
+		fails    bool
+	}{
+		{name: "hour offset", src: `["2024-01-02 03:04:05+00",)`,
+			expected: pg.TstzRange{Lower: &lower, LowerInclusive: true}},
+		{name: "minute offset", src: `(,"2024-01-02 08:34:05.5+05:30"]`,
+			expected: pg.TstzRange{Upper: &upper, UpperInclusive: true}},
+		{name: "infinity", src: `[-infinity,infinity)`, expected: pg.TstzRange{LowerInclusive: true}},
+		{name: "RFC 3339", src: `["2024-01-02T03:04:05Z","2024-01-02T08:34:05.5+05:30")`,
+			expected: pg.TstzRange{Lower: &lower, Upper: &upper, LowerInclusive: true}},
+		{name: "empty", src: "empty", expected: pg.TstzRange{Empty: true}},
+		{name: "invalid bound", src: `["yesterday",)`, fails: true},
+	}
+
+	for _, c := range cases {
+		var r pg.TstzRange
+		err := r.Scan(c.src)
+		if c.fails {
+			if err == nil {
+				t.Fatalf("%s: expected error, got %+v", c.name, r)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !rangesEqual(r, c.expected) {
+			t.Fatalf("%s: expected %+v, got %+v", c.name, c.expected, r)
+		}
+
+		// values scan back to the same range
+		val, err := r.Value()
+		if err != nil {
+			t.Fatalf("%s: value: %v", c.name, err)
+		}
+		var back pg.TstzRange
+		if err := back.Scan(val); err != nil || !rangesEqual(back, r) {
+			t.Fatalf("%s: expected %+v after round trip, got %+v, %v", c.name, r, back, err)
+		}
+	}
+}
+
+func rangesEqual(a, b pg.TstzRange) bool {
+	timeEqual := func(x, y *time.Time) bool {
+		return x == nil && y == nil || x != nil && y != nil && x.Equal(*y)
+	}
+	return a.Empty == b.Empty && a.LowerInclusive == b.LowerInclusive && a.UpperInclusive == b.UpperInclusive &&
+		timeEqual(a.Lower, b.Lower) && timeEqual(a.Upper, b.Upper)
+}
@@ -0,0 +1,84 @@
+package pg_dao
+
+import (
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// SearchOptions configures Search.
+type SearchOptions struct {
+	// Config is the text search configuration, e.g. "english". default_text_search_config
+	// of the server is used if empty.
+	Config string
+	// Vector is a stored tsvector column, e.g. a generated column covered by a GIN index,
+	// matched instead of to_tsvector of the searched columns.
+	Vector string
+	// NoRank disables ordering by ts_rank, so rows are ordered by OrderBy calls only.
+	NoRank bool
+	// Headline is the name of the selected ts_headline snippet of the searched columns, scanned
+	// into the field tagged with it, e.g. of a struct embedding the DTO.
+	Headline string
+	// HeadlineOptions are ts_headline options, e.g. "MaxWords=20, MinWords=5".
+	HeadlineOptions string
+}
+
+// Search matches rows whose text columns match the query written in web search syntax,
+// e.g. `"exact phrase" -excluded or alternative`, with websearch_to_tsquery. Rows are ordered by
+// ts_rank unless opts.NoRank is set; ranking and headlines are skipped by Count.
+func (d *dao) Search(cols []string, query string, opts SearchOptions) DAO {
+	if len(cols) == 0 && (opts.Vector == "" || opts.Headline != "") {
+		d.setErr(errors.New("columns to search are required"))
+		return d
+	}
+
+	document := searchDocument(cols)
+	vector := sq.Expr(opts.Vector)
+	if opts.Vector == "" {
+		vector = searchFunc("to_tsvector", opts.Config, document)
+	}
+	tsquery := searchFunc("websearch_to_tsquery", opts.Config, sq.Expr("?", query))
+
+	d.sql = d.sql.Where(sq.Expr("? @@ ?", vector, tsquery))
+	if d.count {
+		return d
+	}
+	if !opts.NoRank {
+		d.sql = d.sql.OrderByClause(sq.Expr("ts_rank(?, ?) "+OrderDescending, vector, tsquery))
+	}
+	if opts.Headline != "" {
+		var headline sq.Sqlizer
+		if opts.HeadlineOptions == "" {
+			headline = searchFunc("ts_headline", opts.Config, document, tsquery)
+		} else {
+			headline = searchFunc("ts_headline", opts.Config, document, tsquery, sq.Expr("?", opts.HeadlineOptions))
+		}
+		d.sql = d.sql.Column(sq.Alias(headline, opts.Headline))
+	}
+	return d
+}
+
+// searchDocument concatenates text columns, NULL columns are skipped.
+func searchDocument(cols []string) sq.Sqlizer {
+	parts := make([]string, len(cols))
+	for i, col := range cols {
+		parts[i] = "coalesce(" + col + ", '')"
+	}
+	return sq.Expr(strings.Join(parts, " || ' ' || "))
+}
+
+// searchFunc calls a text search function with the configuration passed as the first argument if set.
+func searchFunc(name, config string, args ...sq.Sqlizer) sq.Sqlizer {
+	placeholders := make([]string, len(args))
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		placeholders[i] = "?"
+		values[i] = arg
+	}
+	if config != "" {
+		placeholders = append([]string{"?::regconfig"}, placeholders...)
+		values = append([]interface{}{config}, values...)
+	}
+	return sq.Expr(name+"("+strings.Join(placeholders, ", ")+")", values...)
+}
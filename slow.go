@@ -0,0 +1,107 @@
+package pg_dao
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3"
+)
+
+// SlowQuery describes a statement which execution exceeded the slow query threshold.
+type SlowQuery struct {
+	Table    string
+	SQL      string
+	Duration time.Duration
+	// Caller is the first function outside of pg-dao in the call stack, formatted as "func (file:line)".
+	Caller string
+	// Plan is the output of EXPLAIN (FORMAT JSON) if enabled with WithSlowQueryExplain.
+	Plan []byte
+}
+
+// WithSlowQueryThreshold enables reporting of statements running longer than threshold.
+// Slow queries are logged with WarnLevel to the logger set by WithLogger unless WithSlowQueryReporter is used.
+func WithSlowQueryThreshold(threshold time.Duration) Option {
+	return func(o *options) {
+		o.slowThreshold = threshold
+	}
+}
+
+// WithSlowQueryReporter sets a function slow queries are reported to.
+func WithSlowQueryReporter(report func(ctx context.Context, q SlowQuery)) Option {
+	return func(o *options) {
+		o.slowReporter = report
+	}
+}
+
+// WithSlowQueryExplain enables EXPLAIN (FORMAT JSON) of slow queries. Explain runs on a separate
+// connection, outside of the current transaction, before the query is reported.
+func WithSlowQueryExplain() Option {
+	return func(o *options) {
+		o.slowExplain = true
+	}
+}
+
+var pkgPath = reflect.TypeOf(dao{}).PkgPath()
+
+func (d *dao) checkSlow(ctx context.Context, query string, args []interface{}, duration time.Duration) {
+	if d.opts.slowThreshold <= 0 || duration < d.opts.slowThreshold {
+		return
+	}
+
+	q := SlowQuery{
+		Table:    d.tableName,
+		SQL:      query,
+		Duration: duration,
+		Caller:   caller(),
+	}
+	if d.opts.slowExplain {
+		q.Plan = d.explain(ctx, query, args)
+	}
+
+	if d.opts.slowReporter != nil {
+		d.opts.slowReporter(ctx, q)
+		return
+	}
+	if d.opts.log != nil {
+		d.opts.log.WithFields(logan.F{
+			"table":    q.Table,
+			"sql":      q.SQL,
+			"duration": q.Duration,
+			"caller":   q.Caller,
+			"plan":     string(q.Plan),
+		}).Warn("slow sql statement")
+	}
+}
+
+// explain returns the plan of the query or nil if it can not be explained.
+func (d *dao) explain(ctx context.Context, query string, args []interface{}) []byte {
+	if d.db == nil {
+		return nil
+	}
+
+	var plan []byte
+	stmt := "EXPLAIN (FORMAT JSON) " + query
+	if err := d.db.RawDB().QueryRowContext(ctx, stmt, args...).Scan(&plan); err != nil {
+		return nil
+	}
+	return plan
+}
+
+// caller returns the first function in the call stack outside of this package.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkgPath+".") {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
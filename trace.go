@@ -0,0 +1,106 @@
+package pg_dao
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// A Tracer starts a span for every statement executed by DAO.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// A Span is a single traced statement. End is called with the statement error once it finishes.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	End(err error)
+}
+
+// TraceParenter can be implemented by Span to tag statements with W3C traceparent of the span.
+type TraceParenter interface {
+	TraceParent() string
+}
+
+// WithTracer sets the Tracer used to trace statements.
+func WithTracer(tracer Tracer) Option {
+	return func(o *options) {
+		o.tracer = tracer
+	}
+}
+
+// WithSQLCommenter enables sqlcommenter-style tagging of statements with a trailing comment,
+// e.g. /*route='%2Fentries',service='api'*/. Tags are taken from the context, see ContextWithSQLTags,
+// and traceparent from the current span if it implements TraceParenter.
+func WithSQLCommenter() Option {
+	return func(o *options) {
+		o.commenter = true
+	}
+}
+
+type sqlTagsKey struct{}
+
+// ContextWithSQLTags returns a context carrying tags statements are commented with.
+// Tags are merged with the ones already stored in ctx.
+func ContextWithSQLTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	for k, v := range SQLTagsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, sqlTagsKey{}, merged)
+}
+
+// SQLTagsFromContext returns tags stored with ContextWithSQLTags.
+func SQLTagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(sqlTagsKey{}).(map[string]string)
+	return tags
+}
+
+func (d *dao) startSpan(ctx context.Context, query string) (context.Context, Span) {
+	ctx, span := d.opts.tracer.StartSpan(ctx, fmt.Sprintf("pg-dao %s", d.tableName))
+	span.SetAttribute("db.system", "postgresql")
+	span.SetAttribute("db.sql.table", d.tableName)
+	span.SetAttribute("db.statement", query)
+	return ctx, span
+}
+
+// sqlComment returns the comment statement has to be tagged with or an empty string if there are no tags.
+func sqlComment(ctx context.Context, span Span) string {
+	var traceParent string
+	if tp, ok := span.(TraceParenter); ok {
+		traceParent = tp.TraceParent()
+	}
+
+	tags := SQLTagsFromContext(ctx)
+	pairs := make([]string, 0, len(tags)+1)
+	for k, v := range tags {
+		if k == "traceparent" && traceParent != "" {
+			continue
+		}
+		pairs = append(pairs, sqlTag(k, v))
+	}
+	if traceParent != "" {
+		pairs = append(pairs, sqlTag("traceparent", traceParent))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	sort.Strings(pairs)
+
+	return " /*" + strings.Join(pairs, ",") + "*/"
+}
+
+// sqlTag formats a single tag. Escaping guarantees that neither "*/" nor bind placeholders appear in the comment:
+// url.PathEscape escapes "?" but keeps "$", so it is escaped explicitly.
+func sqlTag(key, value string) string {
+	return fmt.Sprintf("%s='%s'", escapeSQLTag(key), escapeSQLTag(value))
+}
+
+func escapeSQLTag(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "$", "%24")
+}
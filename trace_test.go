@@ -0,0 +1,72 @@
+package pg_dao
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type traceSpan string
+
+func (traceSpan) SetAttribute(string, interface{}) {}
+
+func (traceSpan) End(error) {}
+
+func (s traceSpan) TraceParent() string {
+	return string(s)
+}
+
+type plainSpan struct{}
+
+func (plainSpan) SetAttribute(string, interface{}) {}
+
+func (plainSpan) End(error) {}
+
+func TestSQLComment(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	cases := []struct {
+		name     string
+		tags     map[string]string
+		span     Span
+		expected string
+	}{
+		{name: "no tags", span: plainSpan{}, expected: ""},
+		{name: "sorted", tags: map[string]string{"service": "api", "route": "/entries"}, span: plainSpan{},
+			expected: " /*route='%2Fentries',service='api'*/"},
+		{name: "comment end", tags: map[string]string{"a": "*/ DROP TABLE x; /*"}, span: plainSpan{},
+			expected: " /*a='%2A%2F%20DROP%20TABLE%20x%3B%20%2F%2A'*/"},
+		{name: "quotes", tags: map[string]string{"it's": "'v'"}, span: plainSpan{},
+			expected: " /*it%27s='%27v%27'*/"},
+		{name: "placeholders", tags: map[string]string{"q": "? $1"}, span: plainSpan{},
+			expected: " /*q='%3F%20%241'*/"},
+		{name: "traceparent", span: traceSpan(traceParent), expected: " /*traceparent='" + traceParent + "'*/"},
+		{name: "span overrides tag", tags: map[string]string{"traceparent": "x", "a": "b"}, span: traceSpan(traceParent),
+			expected: " /*a='b',traceparent='" + traceParent + "'*/"},
+		{name: "tag without span traceparent", tags: map[string]string{"traceparent": "x"}, span: traceSpan(""),
+			expected: " /*traceparent='x'*/"},
+	}
+
+	for _, c := range cases {
+		ctx := context.Background()
+		if c.tags != nil {
+			ctx = ContextWithSQLTags(ctx, c.tags)
+		}
+		got := sqlComment(ctx, c.span)
+		if got != c.expected {
+			t.Fatalf("%s: expected %q, got %q", c.name, c.expected, got)
+		}
+		if strings.Count(got, "*/") > 1 || strings.ContainsAny(got, "?$") {
+			t.Fatalf("%s: comment is not escaped: %q", c.name, got)
+		}
+	}
+}
+
+func TestContextWithSQLTagsMerges(t *testing.T) {
+	ctx := ContextWithSQLTags(context.Background(), map[string]string{"a": "1", "b": "2"})
+	ctx = ContextWithSQLTags(ctx, map[string]string{"b": "3"})
+
+	tags := SQLTagsFromContext(ctx)
+	if len(tags) != 2 || tags["a"] != "1" || tags["b"] != "3" {
+		t.Fatalf("expected merged tags, got %v", tags)
+	}
+}
@@ -0,0 +1,277 @@
+package pg_dao
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// JSON stores V as json or jsonb. A nil V is stored as NULL. Scan unmarshals into V
+// if it is a non-nil pointer, otherwise into a new interface{} value.
+type JSON struct {
+	V interface{}
+}
+
+func (j JSON) Value() (driver.Value, error) {
+	if isNil(reflect.ValueOf(j.V)) {
+		return nil, nil
+	}
+	data, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal json")
+	}
+	// lib/pq sends []byte as bytea, so json is passed as text.
+	return string(data), nil
+}
+
+func (j *JSON) Scan(src interface{}) error {
+	data, ok := srcBytes(src)
+	if !ok {
+		return errors.Errorf("can not scan %T into JSON", src)
+	}
+
+	if v := reflect.ValueOf(j.V); v.Kind() != reflect.Ptr || v.IsNil() {
+		var val interface{}
+		j.V = &val
+	}
+	if data == nil {
+		v := reflect.ValueOf(j.V).Elem()
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	return errors.Wrap(json.Unmarshal(data, j.V), "failed to unmarshal json")
+}
+
+// Array returns a Valuer and Scanner of a slice, or a pointer to a slice for scans, stored
+// as a Postgres array. Unlike pq.Array it supports slices of any string, integer, float or bool kind,
+// e.g. []int32 or slices of enums declared as named string types.
+func Array(v interface{}) interface {
+	driver.Valuer
+	sql.Scanner
+} {
+	return array{v: v}
+}
+
+type array struct {
+	v interface{}
+}
+
+func (a array) Value() (driver.Value, error) {
+	v := reflect.Indirect(reflect.ValueOf(a.v))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, errors.Errorf("can not store %T as array", a.v)
+	}
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return nil, nil
+	}
+
+	base, ok := baseArray(v.Type().Elem())
+	if !ok {
+		return pq.GenericArray{A: a.v}.Value()
+	}
+	list := reflect.New(base).Elem()
+	list.Set(reflect.MakeSlice(base, v.Len(), v.Len()))
+	for i := 0; i < v.Len(); i++ {
+		list.Index(i).Set(v.Index(i).Convert(base.Elem()))
+	}
+	return list.Interface().(driver.Valuer).Value()
+}
+
+func (a array) Scan(src interface{}) error {
+	v := reflect.ValueOf(a.v)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("can not scan array into %T", a.v)
+	}
+	v = v.Elem()
+
+	base, ok := baseArray(v.Type().Elem())
+	if !ok {
+		return pq.GenericArray{A: a.v}.Scan(src)
+	}
+	list := reflect.New(base)
+	if err := list.Interface().(sql.Scanner).Scan(src); err != nil {
+		return err
+	}
+	list = list.Elem()
+	if list.IsNil() {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	result := reflect.MakeSlice(v.Type(), list.Len(), list.Len())
+	for i := 0; i < list.Len(); i++ {
+		result.Index(i).Set(list.Index(i).Convert(v.Type().Elem()))
+	}
+	v.Set(result)
+	return nil
+}
+
+var (
+	stringArrayType  = reflect.TypeOf(pq.StringArray{})
+	int64ArrayType   = reflect.TypeOf(pq.Int64Array{})
+	float64ArrayType = reflect.TypeOf(pq.Float64Array{})
+	boolArrayType    = reflect.TypeOf(pq.BoolArray{})
+)
+
+// baseArray returns the pq array type elements of kind elem are converted to.
+func baseArray(elem reflect.Type) (reflect.Type, bool) {
+	if elem.Implements(valuerType) || reflect.PtrTo(elem).Implements(scannerType) {
+		return nil, false
+	}
+	switch elem.Kind() {
+	case reflect.String:
+		return stringArrayType, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64ArrayType, true
+	case reflect.Float32, reflect.Float64:
+		return float64ArrayType, true
+	case reflect.Bool:
+		return boolArrayType, true
+	}
+	return nil, false
+}
+
+// Hstore is a value of hstore column, nil values are stored as NULL. A nil Hstore is stored as NULL.
+type Hstore map[string]*string
+
+func (h Hstore) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(h))
+	for _, k := range keys {
+		val := "NULL"
+		if h[k] != nil {
+			val = quoteHstore(*h[k])
+		}
+		pairs = append(pairs, quoteHstore(k)+"=>"+val)
+	}
+	return strings.Join(pairs, ", "), nil
+}
+
+func (h *Hstore) Scan(src interface{}) error {
+	data, ok := srcBytes(src)
+	if !ok {
+		return errors.Errorf("can not scan %T into Hstore", src)
+	}
+	if data == nil {
+		*h = nil
+		return nil
+	}
+
+	result := make(Hstore)
+	p := hstoreParser{s: string(data)}
+	for p.skipSpaces(); !p.done(); p.skipSpaces() {
+		key, ok := p.quoted()
+		if !ok {
+			return errors.Errorf("invalid hstore key at %d", p.pos)
+		}
+		p.skipSpaces()
+		if !p.consume("=>") {
+			return errors.Errorf("invalid hstore separator at %d", p.pos)
+		}
+		p.skipSpaces()
+		if p.consume("NULL") {
+			result[key] = nil
+		} else {
+			val, ok := p.quoted()
+			if !ok {
+				return errors.Errorf("invalid hstore value at %d", p.pos)
+			}
+			result[key] = &val
+		}
+		p.skipSpaces()
+		if !p.done() && !p.consume(",") {
+			return errors.Errorf("invalid hstore pair separator at %d", p.pos)
+		}
+	}
+	*h = result
+	return nil
+}
+
+func quoteHstore(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+type hstoreParser struct {
+	s   string
+	pos int
+}
+
+func (p *hstoreParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *hstoreParser) skipSpaces() {
+	for !p.done() && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *hstoreParser) consume(token string) bool {
+	if strings.HasPrefix(p.s[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *hstoreParser) quoted() (string, bool) {
+	if !p.consume(`"`) {
+		return "", false
+	}
+	var b strings.Builder
+	for ; !p.done(); p.pos++ {
+		switch c := p.s[p.pos]; c {
+		case '\\':
+			p.pos++
+			if p.done() {
+				return "", false
+			}
+			b.WriteByte(p.s[p.pos])
+		case '"':
+			p.pos++
+			return b.String(), true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+// srcBytes returns text or binary value of scanned src, nil for NULL.
+func srcBytes(src interface{}) ([]byte, bool) {
+	switch src := src.(type) {
+	case nil:
+		return nil, true
+	case []byte:
+		return src, true
+	case string:
+		return []byte(src), true
+	}
+	return nil, false
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package pg_dao_test
+
+import (
+	"reflect"
+	"testing"
+
+	pg "github.com/olegfomenko/pg-dao"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+func TestHstoreScan(t *testing.T) {
+	cases := []struct {
+		name     string
+		src      interface{}
+		expected pg.Hstore
+		fails    bool
+	}{
+		{name: "NULL", src: nil, expected: nil},
+		{name: "empty", src: "", expected: pg.Hstore{}},
+		{name: "pairs", src: []byte(`"a"=>"1", "b"=>NULL`), expected: pg.Hstore{"a": str("1"), "b": nil}},
+		{name: "escapes", src: `"k \"q\""=>"c:\\dir", "x,y"=>"=>"`,
+			expected: pg.Hstore{`k "q"`: str(`c:\dir`), "x,y": str("=>")}},
+		{name: "quoted NULL", src: `"a"=>"NULL"`, expected: pg.Hstore{"a": str("NULL")}},
+		{name: "spaces", src: `  "a" => "" ,"b"=>" "  `, expected: pg.Hstore{"a": str(""), "b": str(" ")}},
+		{name: "unquoted key", src: `a=>"1"`, fails: true},
+		{name: "missing separator", src: `"a" "1"`, fails: true},
+		{name: "unterminated value", src: `"a"=>"1`, fails: true},
+		{name: "missing comma", src: `"a"=>"1" "b"=>"2"`, fails: true},
+		{name: "dangling escape", src: `"a"=>"1\`, fails: true},
+		{name: "unsupported source", src: 1, fails: true},
+	}
+
+	for _, c := range cases {
+		var h pg.Hstore
+		err := h.Scan(c.src)
+		if c.fails {
+			if err == nil {
+				t.Fatalf("%s: expected error, got %v", c.name, h)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(h, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, h)
+		}
+	}
+}
+
+func TestHstoreValue(t *testing.T) {
+	cases := []struct {
+		name     string
+		h        pg.Hstore
+		expected interface{}
+	}{
+		{name: "nil", h: nil, expected: nil},
+		{name: "empty", h: pg.Hstore{}, expected: ""},
+		{name: "sorted", h: pg.Hstore{"b": nil, "a": str("1")}, expected: `"a"=>"1", "b"=>NULL`},
+		{name: "escapes", h: pg.Hstore{`k "q"`: str(`c:\dir`)}, expected: `"k \"q\""=>"c:\\dir"`},
+	}
+
+	for _, c := range cases {
+		val, err := c.h.Value()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if val != c.expected {
+			t.Fatalf("%s: expected %q, got %q", c.name, c.expected, val)
+		}
+
+		// values scan back to the same hstore
+		var h pg.Hstore
+		if err := h.Scan(val); err != nil {
+			t.Fatalf("%s: scan: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(h, c.h) {
+			t.Fatalf("%s: expected %v after round trip, got %v", c.name, c.h, h)
+		}
+	}
+}
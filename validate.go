@@ -0,0 +1,122 @@
+package pg_dao
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/olegfomenko/pg-dao/introspect"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// ValidationError lists mismatches between a DTO and the table it is stored in.
+type ValidationError struct {
+	Table    string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("dto does not match table %s: %s", e.Table, strings.Join(e.Problems, "; "))
+}
+
+// Validate checks that every field of dto read and written with `db` tags maps to an existing column
+// of a compatible type, and that all NOT NULL columns without defaults are written. Tables qualified
+// with a schema are looked up in it, others in the current schema. Mismatches are returned
+// as *ValidationError. Intended to be called on startup.
+func (d *dao) Validate(ctx context.Context, dto interface{}) error {
+	if d.db == nil {
+		return errors.New("database is required to validate dto")
+	}
+
+	t := reflect.TypeOf(dto)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return errors.New("argument is not a struct")
+	}
+
+	table := d.tableName
+	var opts []introspect.Option
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		opts = append(opts, introspect.WithSchema(table[:i]))
+		table = table[i+1:]
+	}
+	columns, err := introspect.New(d.db, opts...).Columns(ctx, table)
+	if err != nil {
+		return err
+	}
+	verr := &ValidationError{Table: d.tableName}
+	if len(columns) == 0 {
+		verr.Problems = append(verr.Problems, "table does not exist")
+		return verr
+	}
+
+	byName := make(map[string]introspect.Column, len(columns))
+	for _, c := range columns {
+		byName[c.Name] = c
+	}
+
+	// fields both read and written are reported once
+	reported := make(map[string]bool)
+	report := func(format string, args ...interface{}) {
+		problem := fmt.Sprintf(format, args...)
+		if !reported[problem] {
+			reported[problem] = true
+			verr.Problems = append(verr.Problems, problem)
+		}
+	}
+	check := func(fields []field, read bool) {
+		for _, f := range fields {
+			sf := t.FieldByIndex(f.index)
+			c, ok := byName[f.column]
+			switch {
+			case !ok:
+				report("field %s: column %s does not exist", sf.Name, f.column)
+			case !f.compatible(c):
+				report("field %s: type %s is not compatible with column %s of type %s", sf.Name, sf.Type, f.column, c.UDTName)
+			case read && c.Nullable && f.codec == codecNone && !introspect.Nullable(sf.Type):
+				report("field %s: type %s can not hold NULL of column %s", sf.Name, sf.Type, f.column)
+			}
+		}
+	}
+	check(mappingOf(t, false).fields, true)
+
+	writes := mappingOf(t, d.opts.structsTags).writes
+	check(writes, false)
+
+	written := make(map[string]bool, len(writes))
+	for _, f := range writes {
+		written[f.column] = true
+	}
+	for _, c := range columns {
+		if written[c.Name] || !c.Required() || d.managed(c.Name) {
+			continue
+		}
+		report("column %s is NOT NULL and has no default, but is not written", c.Name)
+	}
+
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// managed reports whether the column value is set by DAO itself.
+func (d *dao) managed(col string) bool {
+	return col == d.opts.createdAt || col == d.opts.version
+}
+
+// compatible reports whether the field can be written to and read from the column.
+func (f field) compatible(c introspect.Column) bool {
+	switch f.codec {
+	case codecJSON:
+		return c.UDTName == "json" || c.UDTName == "jsonb"
+	case codecArray:
+		return c.IsArray()
+	case codecHstore:
+		return c.UDTName == "hstore"
+	}
+	return introspect.Compatible(f.typ, c)
+}